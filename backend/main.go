@@ -1,31 +1,50 @@
 package main
 
 import (
+	"archive/zip"
 	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"html/template"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
+	"github.com/skip2/go-qrcode"
 
 	storage "go-uploader/storage"
-	alioss "go-uploader/storage/ali-oss"
-	"go-uploader/storage/minio"
+	_ "go-uploader/storage/ali-oss"
+	_ "go-uploader/storage/azure-blob"
+	_ "go-uploader/storage/minio"
+	_ "go-uploader/storage/s3"
+	_ "go-uploader/storage/sftp"
+	_ "go-uploader/storage/webdav"
 	"go-uploader/utils"
 )
 
 // 全局变量用于控制日志级别
 var (
 	verbose     bool   // 详细日志模式
-	storageType string // 存储类型: ali-oss, minio
+	storageType string // 存储类型: ali-oss, azure-blob, minio, s3, sftp, webdav
 	logger      *utils.Logger
 )
 
@@ -38,18 +57,41 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// 服务端渲染的页面模板
+//
+//go:embed templates/*.html
+var templateFS embed.FS
+
 // 初始化短链接管理器
 var shortLinkManager = utils.NewShortLinkManager()
 
+// 初始化短链接访问统计管理器
+var linkStatsManager = utils.NewLinkStatsManager()
+
 // 初始化上传进度管理器
 var progressManager *utils.ProgressManager
 
+// 初始化批量上传会话管理器
+var batchManager *utils.BatchManager
+
+// 初始化上传任务登记器，用于取消、暂停和恢复正在进行的上传
+var uploadTracker = utils.NewUploadTracker()
+
+// 初始化上传调度器
+var uploadScheduler *utils.UploadScheduler
+
+// 初始化异步上传任务管理器
+var jobManager *utils.JobManager
+
+// 初始化带宽限速器
+var bandwidthThrottle *utils.BandwidthThrottle
+
 // 初始化解析命令行参数
 func init() {
 	// 添加verbose标志
 	flag.BoolVar(&verbose, "verbose", false, "启用详细日志输出模式")
 	// 添加存储类型标志
-	flag.StringVar(&storageType, "storage", "", "存储类型: ali-oss, minio")
+	flag.StringVar(&storageType, "storage", "", "存储类型: ali-oss, azure-blob, minio, s3, sftp, webdav")
 	flag.Parse()
 }
 
@@ -70,10 +112,17 @@ func main() {
 
 	// 初始化日志记录器
 	var err error
-	logger, err = utils.NewLogger(verbose)
+	// migrate 子命令在控制台输出进度，日志同时写入控制台
+	migrating := flag.Arg(0) == "migrate"
+	logger, err = utils.NewLogger(verbose || migrating)
 	if err != nil {
 		log.Fatalf("初始化日志记录器失败: %v", err)
 	}
+
+	// migrate 子命令：在两个存储后端之间迁移对象后退出，不启动HTTP服务
+	if migrating {
+		os.Exit(runMigrate(flag.Args()[1:]))
+	}
 	logger.Printf("应用启动, 详细日志模式: %v", verbose)
 	logger.Printf("使用存储类型: %s", storageType)
 
@@ -81,6 +130,24 @@ func main() {
 	progressManager = utils.NewProgressManager(logger)
 	logger.Printf("上传进度管理器初始化成功")
 
+	// 初始化批量上传会话管理器
+	batchManager = utils.NewBatchManager(progressManager, logger)
+
+	// 初始化上传调度器，限制同时上传的文件数和合计分片并行数
+	schedulerConfig := utils.LoadSchedulerConfigFromEnv()
+	uploadScheduler = utils.NewUploadScheduler(schedulerConfig)
+	logger.Printf("上传调度器初始化成功: 最大并发上传=%d, 最大分片并行数=%d, 单个上传分片并行数=%d, 最大排队数=%d",
+		schedulerConfig.MaxConcurrent, schedulerConfig.MaxParts, schedulerConfig.PartsPerUpload, schedulerConfig.MaxQueue)
+
+	// 初始化带宽限速器，全局、每个客户端IP和每个上传的限速同时生效
+	throttleConfig, err := utils.LoadThrottleConfigFromEnv()
+	if err != nil {
+		logger.Fatalf("加载带宽限速配置失败: %v", err)
+	}
+	bandwidthThrottle = utils.NewBandwidthThrottle(throttleConfig)
+	logger.Printf("带宽限速器初始化成功: 全局=%d KB/s, 每个IP=%d KB/s, 每个上传=%d KB/s, 时段规则=%d条 (0表示不限速)",
+		throttleConfig.GlobalKBps, throttleConfig.PerIPKBps, throttleConfig.PerUploadKBps, len(throttleConfig.Schedule))
+
 	// 如果是详细日志模式，设置Gin为调试模式
 	if verbose {
 		gin.SetMode(gin.DebugMode)
@@ -112,38 +179,7 @@ func main() {
 		gin.DefaultWriter = f
 	}
 
-	// 加载存储配置并创建存储服务
-	var storageConfig storage.StorageConfig
-	logger.Printf("加载存储服务配置中，存储类型: %s...", storageType)
-
-	switch storageType {
-	case "ali-oss":
-		ossConfig, err := alioss.LoadAliOSSConfigFromEnv()
-		if err != nil {
-			logger.Fatalf("加载阿里云OSS配置失败: %v", err)
-		}
-		storageConfig = ossConfig
-		logger.Printf("阿里云OSS配置加载成功, 区域: %s, 存储桶: %s",
-			ossConfig.Region, ossConfig.BucketName)
-	case "minio":
-		minioConfig, err := minio.LoadMinioConfigFromEnv()
-		if err != nil {
-			logger.Fatalf("加载MinIO配置失败: %v", err)
-		}
-		storageConfig = minioConfig
-		logger.Printf("MinIO配置加载成功, 端点: %s, 存储桶: %s",
-			minioConfig.Endpoint, minioConfig.BucketName)
-	default:
-		logger.Fatalf("不支持的存储类型: %s", storageType)
-	}
-
-	// 初始化存储服务
-	logger.Printf("初始化存储服务...")
-	storageService, err := storage.CreateStorageService(storageConfig)
-	if err != nil {
-		logger.Fatalf("初始化存储服务失败: %v", err)
-	}
-	logger.Printf("存储服务初始化成功")
+	storageRouter, storageBackends, proxySigner := setupStorageBackends()
 
 	// 确保checkpoint目录存在
 	checkpointDir := "./checkpoint"
@@ -154,11 +190,33 @@ func main() {
 		}
 	}
 
+	// 确保临时目录存在，就绪检查需要检查其可用空间
+	tempDir := "./temp"
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		logger.Printf("创建临时目录失败: %v", err)
+	}
+	minFreeDisk := utils.MinFreeDiskFromEnv()
+
+	// 初始化异步上传任务管理器，任务保存在checkpoint目录中，并恢复重启前未完成的任务
+	jobManager, err = utils.NewJobManager(filepath.Join(checkpointDir, "jobs"))
+	if err != nil {
+		logger.Fatalf("初始化异步上传任务管理器失败: %v", err)
+	}
+	resumeUploadJobs(storageRouter)
+
 	// 创建Gin路由
 	r := gin.New() // 使用New而不是Default以便自定义中间件
 
 	// 添加Recovery中间件
-	r.Use(gin.Recovery())
+	// 流式响应在响应头发送后出错时，处理函数通过panic(http.ErrAbortHandler)让net/http中断连接，
+	// 这里需要继续抛出，否则响应会正常结束，客户端会把不完整的文件当作下载成功
+	r.Use(gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		if err == http.ErrAbortHandler {
+			panic(err)
+		}
+		logger.Printf("处理请求时发生panic: %v\n%s", err, debug.Stack())
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
 
 	// 添加自定义日志中间件
 	r.Use(func(c *gin.Context) {
@@ -201,7 +259,7 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, Authorization, X-Share-Password, X-Upload-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -219,6 +277,9 @@ func main() {
 	r.StaticFile("/", "./index.html")
 	logger.Printf("静态文件路由设置完成")
 
+	// 加载服务端渲染的页面模板
+	r.SetHTMLTemplate(template.Must(template.ParseFS(templateFS, "templates/*.html")))
+
 	// WebSocket处理上传进度
 	r.GET("/api/ws/progress/:id", func(c *gin.Context) {
 		id := c.Param("id")
@@ -229,31 +290,207 @@ func main() {
 			logger.Printf("WebSocket升级失败: %v", err)
 			return
 		}
-		defer conn.Close()
 
-		// 注册连接
-		progressManager.RegisterConn(id, conn)
+		// 订阅进度并保持连接直到客户端断开，同一上传ID可以被多个连接同时订阅
 		logger.Printf("WebSocket连接已注册: ID=%s", id)
-		defer progressManager.RemoveConn(id)
+		progressManager.ServeWebSocket(id, conn, func(message []byte) {
+			// 客户端可以通过进度连接发送控制消息: {"action": "cancel|pause|resume"}
+			var control struct {
+				Action string `json:"action"`
+			}
+			if err := json.Unmarshal(message, &control); err != nil {
+				logger.Printf("无法解析WebSocket控制消息: ID=%s, %v", id, err)
+				return
+			}
+			if err := controlUpload(id, control.Action); err != nil {
+				logger.Printf("处理WebSocket控制消息失败: ID=%s, 操作=%s, %v", id, control.Action, err)
+			}
+		})
+	})
 
-		// 保持WebSocket连接直到客户端断开
-		for {
-			_, _, err := conn.ReadMessage()
-			if err != nil {
-				logger.Printf("WebSocket连接关闭: ID=%s, 错误: %v", id, err)
-				break
+	// 取消、暂停或恢复正在进行的上传
+	r.POST("/api/uploads/:id/:action", func(c *gin.Context) {
+		id := c.Param("id")
+		action := c.Param("action")
+		logger.Printf("收到上传控制请求: ID=%s, 操作=%s", id, action)
+
+		if err := controlUpload(id, action); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, utils.ErrUploadNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{
+				"error":  "Failed to control upload",
+				"detail": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"uploadID": id,
+			"action":   action,
+		})
+	})
+
+	// 轮询获取上传进度，供无法使用WebSocket的客户端和脚本使用
+	r.GET("/api/progress/:id", func(c *gin.Context) {
+		id := c.Param("id")
+
+		progress, exists := progressManager.GetProgress(id)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "No progress found for upload ID",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, progress)
+	})
+
+	// 通过Server-Sent Events推送上传进度，适用于会剥离WebSocket升级请求的代理
+	r.GET("/api/progress/:id/stream", func(c *gin.Context) {
+		id := c.Param("id")
+		logger.Printf("SSE进度订阅请求: ID=%s", id)
+
+		sub := progressManager.Subscribe(id)
+		defer sub.Close()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // 关闭Nginx等反向代理的缓冲
+
+		// 定期发送注释行保活，避免代理因空闲断开连接
+		keepalive := time.NewTicker(15 * time.Second)
+		defer keepalive.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case progress := <-sub.C():
+				c.SSEvent("progress", progress)
+				// 收到终止状态后结束推送
+				return !progress.IsFinal()
+			case <-keepalive.C:
+				_, _ = io.WriteString(w, ": keepalive\n\n")
+				return true
+			case <-sub.Done():
+				return false
+			case <-c.Request.Context().Done():
+				return false
 			}
+		})
+		logger.Printf("SSE进度订阅结束: ID=%s", id)
+	})
+
+	// 创建批量上传会话，请求体为文件清单
+	r.POST("/api/batches", func(c *gin.Context) {
+		logger.Printf("收到创建批量上传会话请求")
+
+		var req struct {
+			Files []utils.BatchFile `json:"files"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Invalid batch manifest",
+				"detail": err.Error(),
+			})
+			return
+		}
+
+		summary, err := batchManager.CreateBatch(req.Files)
+		if err != nil {
+			logger.Printf("创建批量上传会话失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Invalid batch manifest",
+				"detail": err.Error(),
+			})
+			return
+		}
+
+		logger.Printf("批量上传会话已创建: %s, 文件数: %d, 总大小: %d 字节", summary.ID, summary.TotalFiles, summary.TotalBytes)
+		c.JSON(http.StatusCreated, summary)
+	})
+
+	// 获取批量上传会话的汇总信息和每个文件的状态
+	r.GET("/api/batches/:id", func(c *gin.Context) {
+		summary, exists := batchManager.GetBatch(c.Param("id"))
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Batch not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, summary)
+	})
+
+	// 获取异步上传任务的状态，上传进行中时附带最新的进度
+	r.GET("/api/jobs/:id", func(c *gin.Context) {
+		job, exists := jobManager.GetJob(c.Param("id"))
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Job not found",
+			})
+			return
+		}
+
+		response := gin.H{"job": job}
+		if progress, ok := progressManager.GetProgress(job.UploadID); ok && !job.IsFinished() {
+			response["progress"] = progress
 		}
+		c.JSON(http.StatusOK, response)
 	})
 
 	// 设置文件上传路由
 	r.POST("/api/upload", func(c *gin.Context) {
 		logger.Printf("收到文件上传请求")
 
-		// 获取上传ID
-		uploadID := c.PostForm("uploadID")
+		// 批量上传时查询参数带有文件名的，在接收请求体之前关联到会话，接收阶段的进度和失败也计入会话；
+		// 只在表单中提供会话ID或文件名的旧版客户端在解析表单后关联
+		batchID := c.Query("batchID")
+		batchFileName := c.Query("fileName")
+		failBatchFile := func(err error) {
+			if batchID == "" || batchFileName == "" {
+				return
+			}
+			if err := batchManager.FailFile(batchID, batchFileName, err); err != nil {
+				logger.Printf("标记批量上传文件失败出错: %s, %v", batchID, err)
+			}
+		}
+
+		// 上传队列已满时在接收请求体之前拒绝，避免浪费带宽
+		if uploadScheduler.QueueFull() {
+			logger.Printf("上传队列已满，拒绝上传请求")
+			failBatchFile(utils.ErrQueueFull)
+			rejectQueueFull(c)
+			return
+		}
+
+		// 按全局、客户端IP和上传自身的带宽限制读取请求体，rateLimit参数 (KB/s) 只能进一步降低限速
+		rateLimit, _ := strconv.Atoi(c.Query("rateLimit"))
+		limiter := uploadRateLimiter(c.ClientIP(), rateLimit)
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{storage.NewThrottledReader(c.Request.Context(), c.Request.Body, limiter), c.Request.Body}
+
+		// 优先从查询参数或请求头获取上传ID，这样在解析请求体之前就能上报接收进度
+		uploadID := c.Query("uploadID")
+		if uploadID == "" {
+			uploadID = c.GetHeader("X-Upload-ID")
+		}
+		if uploadID != "" {
+			receiveID := uploadID
+			c.Request.Body = utils.NewCountingReader(c.Request.Body, c.Request.ContentLength, func(increment, received, total int64) {
+				progressManager.UpdateReceived(receiveID, increment, received, total)
+			})
+		} else {
+			// 兼容旧版客户端：上传ID放在表单中，只能上报存储阶段的进度
+			uploadID = c.PostForm("uploadID")
+		}
 		if uploadID == "" {
 			logger.Printf("上传ID为空")
+			failBatchFile(errors.New("缺少上传ID"))
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "No upload ID provided",
 			})
@@ -261,6 +498,43 @@ func main() {
 		}
 		logger.Printf("上传ID: %s", uploadID)
 
+		// 登记上传任务，客户端断开时取消上传；异步上传在返回任务ID后由后台协程接管
+		upload, err := uploadTracker.Register(context.Background(), uploadID)
+		if err != nil {
+			logger.Printf("登记上传任务失败: %s, %v", uploadID, err)
+			c.JSON(http.StatusConflict, gin.H{
+				"error":  "Upload already in progress",
+				"detail": err.Error(),
+			})
+			return
+		}
+		stopCancelOnDisconnect := context.AfterFunc(c.Request.Context(), upload.Cancel)
+		detached := false
+		defer func() {
+			if !detached {
+				stopCancelOnDisconnect()
+				uploadTracker.Unregister(uploadID)
+			}
+		}()
+
+		progressManager.Reset(uploadID)
+
+		// 之后的每个失败分支都会发送失败的进度，会话据此将文件标记为失败
+		attached := false
+		if batchID != "" && batchFileName != "" {
+			if err := batchManager.Attach(batchID, batchFileName, 0, uploadID); err != nil {
+				logger.Printf("关联批量上传会话失败: %s, %v", batchID, err)
+				progressManager.Fail(uploadID, batchFileName, err)
+				c.JSON(http.StatusNotFound, gin.H{
+					"error":  "Batch not found",
+					"detail": err.Error(),
+				})
+				return
+			}
+			attached = true
+			logger.Printf("文件已关联到批量上传会话: %s", batchID)
+		}
+
 		// 获取原始文件名，如果有的话（用于文件夹上传）
 		originalFileName := c.PostForm("originalFileName")
 
@@ -268,6 +542,7 @@ func main() {
 		file, err := c.FormFile("file")
 		if err != nil {
 			logger.Printf("获取上传文件失败: %v", err)
+			progressManager.Fail(uploadID, "", err)
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":  "No file uploaded",
 				"detail": err.Error(),
@@ -283,8 +558,37 @@ func main() {
 			objectName = originalFileName
 		}
 
+		// 旧版客户端在表单中提供会话ID，只能在解析表单后关联，会话不包含接收阶段的进度
+		if batchID == "" {
+			batchID = c.PostForm("batchID")
+		}
+		if batchID != "" && !attached {
+			if err := batchManager.Attach(batchID, objectName, file.Size, uploadID); err != nil {
+				logger.Printf("关联批量上传会话失败: %s, %v", batchID, err)
+				progressManager.Fail(uploadID, objectName, err)
+				c.JSON(http.StatusNotFound, gin.H{
+					"error":  "Batch not found",
+					"detail": err.Error(),
+				})
+				return
+			}
+			logger.Printf("文件已关联到批量上传会话: %s", batchID)
+		}
+
+		// 选择存储后端：backend参数指定的后端，未指定时按路由规则选择
+		backendName := c.Query("backend")
+		if backendName == "" {
+			backendName = c.PostForm("backend")
+		}
+		backendName, storageService, ok := resolveStorageBackend(c, storageRouter, backendName, objectName, file.Size)
+		if !ok {
+			progressManager.Fail(uploadID, objectName, storage.ErrStorageNotFound)
+			return
+		}
+		logger.Printf("存储后端: %s", backendName)
+
 		// 检查文件是否已存在于存储服务中
-		exists, err := storageService.IsObjectExist(context.Background(), objectName)
+		exists, err := storageService.IsObjectExist(upload.Context(), objectName)
 		if err != nil {
 			logger.Printf("检查文件是否存在失败: %v", err)
 			// 继续处理，不中断上传流程
@@ -293,26 +597,29 @@ func main() {
 		// 如果文件已存在，告知用户并提供分享链接选项
 		if exists {
 			logger.Printf("文件 %s 已存在于存储中，不需要重新上传", objectName)
-			bucketDomain := storageService.GetBucketDomain()
-			url := "https://" + bucketDomain + "/" + objectName
-			c.JSON(http.StatusOK, gin.H{
+			url := objectURL(storageService, objectName)
+			response := gin.H{
 				"message":       "File already exists",
 				"filename":      objectName,
 				"size":          file.Size,
 				"url":           url,
+				"backend":       backendName,
 				"alreadyExists": true,
 				"skipUpload":    true,
-			})
+			}
+			placeObject(storageRouter, objectName, backendName)
+			progressManager.Skip(uploadID, objectName, response)
+			c.JSON(http.StatusOK, response)
 			return
 		}
 
 		// 文件不存在，继续上传流程
 		// 创建临时目录保存上传的文件
-		tempDir := "./temp"
 		if _, err := os.Stat(tempDir); os.IsNotExist(err) {
 			logger.Printf("创建临时目录: %s", tempDir)
 			if err := os.Mkdir(tempDir, 0755); err != nil {
 				logger.Printf("创建临时目录失败: %v", err)
+				progressManager.Fail(uploadID, objectName, err)
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error":  "Failed to create temp directory",
 					"detail": err.Error(),
@@ -321,14 +628,16 @@ func main() {
 			}
 		}
 
-		// 生成临时文件路径
-		tempFilePath := filepath.Join(tempDir, file.Filename)
+		// 生成临时文件路径，加上上传ID避免同名文件的并发上传互相覆盖
+		tempFilePath := filepath.Join(tempDir, filepath.Base(uploadID)+"_"+filepath.Base(file.Filename))
 		logger.Printf("临时文件路径: %s", tempFilePath)
 
 		// 保存上传的文件到临时目录
 		logger.Printf("保存文件到临时目录...")
+		progressManager.SetPhase(uploadID, objectName, utils.PhaseStaging)
 		if err := c.SaveUploadedFile(file, tempFilePath); err != nil {
 			logger.Printf("保存文件到临时目录失败: %v", err)
+			progressManager.Fail(uploadID, objectName, err)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":  "Failed to save file",
 				"detail": err.Error(),
@@ -337,59 +646,212 @@ func main() {
 		}
 		logger.Printf("文件已保存到临时目录")
 
-		// 创建进度回调函数
-		progressCallback := func(increment, transferred, total int64) {
-			progressManager.UpdateProgress(uploadID, objectName, increment, transferred, total)
+		// 异步模式：文件保存到临时目录后立即返回任务ID，由后台协程完成向存储服务的上传，
+		// 避免客户端在服务器→存储服务阶段因负载均衡器的空闲超时而断开
+		if async, _ := strconv.ParseBool(c.DefaultQuery("async", c.PostForm("async"))); async {
+			job, err := jobManager.CreateJob(utils.UploadJob{
+				UploadID:   uploadID,
+				ObjectName: objectName,
+				LocalFile:  tempFilePath,
+				Size:       file.Size,
+				Backend:    backendName,
+				ClientIP:   c.ClientIP(),
+				RateLimit:  rateLimit,
+			})
+			if err != nil {
+				logger.Printf("创建异步上传任务失败: %v", err)
+				progressManager.Fail(uploadID, objectName, err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":  "Failed to create upload job",
+					"detail": err.Error(),
+				})
+				return
+			}
+			logger.Printf("创建异步上传任务: %s, 文件: %s", job.ID, objectName)
+
+			// 上传任务不再随请求结束而取消，由后台协程负责注销
+			stopCancelOnDisconnect()
+			detached = true
+			go runUploadJob(storageRouter, storageService, job, upload)
+
+			c.JSON(http.StatusAccepted, gin.H{
+				"message":   "File accepted, uploading in background",
+				"jobID":     job.ID,
+				"uploadID":  uploadID,
+				"filename":  objectName,
+				"size":      file.Size,
+				"backend":   backendName,
+				"statusURL": "/api/jobs/" + job.ID,
+			})
+			return
+		}
 
-			if verbose && (transferred == total || transferred%(total/10) < increment) {
-				percentage := int(float64(transferred) / float64(total) * 100)
-				logger.Printf("上传进度: %s - %d%% (%d/%d 字节)", objectName, percentage, transferred, total)
+		response, transferErr := transferToStorage(storageService, upload, limiter, uploadID, objectName, tempFilePath, file.Size, false)
+		if transferErr != nil {
+			if transferErr.status == http.StatusTooManyRequests {
+				rejectQueueFull(c)
+				return
+			}
+			c.JSON(transferErr.status, gin.H{
+				"error":  transferErr.message,
+				"detail": transferErr.err.Error(),
+			})
+			return
+		}
+		placeObject(storageRouter, objectName, backendName)
+		response["backend"] = backendName
+		if replicated, ok := storageService.(*storage.ReplicatedService); ok {
+			if record, err := replicated.ReplicationStatus(objectName); err == nil {
+				response["replication"] = record.Replicas
 			}
 		}
+		c.JSON(http.StatusOK, response)
+	})
 
-		// 上传文件到存储服务
-		logger.Printf("开始上传文件到存储服务...")
-		startTime := time.Now()
-		result, err := storageService.UploadFile(context.Background(), objectName, tempFilePath, progressCallback)
+	// 查询对象在复制后端各副本上的复制状态
+	r.GET("/api/replication", func(c *gin.Context) {
+		backendName, storageService, ok := locateStorageBackend(c, storageRouter, c.Query("backend"), c.Query("object"))
+		if !ok {
+			return
+		}
+		replicated, ok := storageService.(*storage.ReplicatedService)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Storage backend is not replicated",
+				"backend": backendName,
+			})
+			return
+		}
+
+		objectName := strings.TrimPrefix(c.Query("object"), "/")
+		if objectName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Object name is required"})
+			return
+		}
+		record, err := replicated.ReplicationStatus(objectName)
+		if errors.Is(err, storage.ErrObjectNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":  "Replication record not found",
+				"detail": err.Error(),
+			})
+			return
+		}
 		if err != nil {
-			logger.Printf("上传文件到存储服务失败: %v", err)
+			logger.Printf("读取复制记录失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":  "Failed to upload file",
+				"error":  "Failed to read replication status",
 				"detail": err.Error(),
 			})
 			return
 		}
-		elapsedTime := time.Since(startTime)
-		logger.Printf("文件上传完成: %s, 用时: %v", objectName, elapsedTime)
-
-		// 删除临时文件
-		logger.Printf("删除临时文件: %s", tempFilePath)
-		if err := os.Remove(tempFilePath); err != nil {
-			logger.Printf("删除临时文件失败: %v", err)
-		}
-
-		// 返回上传结果
-		bucketDomain := storageService.GetBucketDomain()
-		url := "https://" + bucketDomain + "/" + objectName
-		logger.Printf("上传成功, 文件URL: %s", url)
 		c.JSON(http.StatusOK, gin.H{
-			"message":  "File uploaded successfully",
-			"filename": objectName,
-			"size":     file.Size,
-			"url":      url,
-			"result":   result,
+			"backend":     backendName,
+			"replicas":    replicated.Replicas(),
+			"replication": record,
 		})
 	})
 
 	// 添加一个健康检查路由
 	r.GET("/api/health", func(c *gin.Context) {
 		logger.Printf("收到健康检查请求")
+		retries := make(map[string]storage.RetryStats, len(storageBackends))
+		circuits := make(map[string]storage.BreakerStats, len(storageBackends))
+		for _, backend := range storageBackends {
+			// 复制后端本身没有重试和熔断，状态见其各个副本
+			if backend.breaker == nil {
+				continue
+			}
+			retries[backend.name] = backend.retry.Stats()
+			circuits[backend.name] = backend.breaker.Stats()
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":         "ok",
+			"time":           time.Now().Format(time.RFC3339),
+			"uploads":        uploadScheduler.Stats(),
+			"backends":       storageRouter.Names(),
+			"defaultBackend": storageRouter.DefaultName(),
+			"retries":        retries,
+			"circuit":        circuits,
+		})
+	})
+
+	// 存活检查：进程能够处理请求即返回成功，不检查依赖
+	r.GET("/api/health/live", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "ok",
 			"time":   time.Now().Format(time.RFC3339),
 		})
 	})
 
+	// 就绪检查：存储服务可用、临时目录空间充足、断点续传目录可写时才返回成功，否则返回503
+	r.GET("/api/health/ready", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+		defer cancel()
+
+		ready := true
+		check := func(err error, details gin.H) gin.H {
+			details["status"] = "ok"
+			if err != nil {
+				ready = false
+				details["status"] = "error"
+				details["error"] = err.Error()
+			}
+			return details
+		}
+
+		// 并行检查所有存储后端，任何一个不可用都视为未就绪
+		probeErrs := make([]error, len(storageBackends))
+		var wg sync.WaitGroup
+		for i, backend := range storageBackends {
+			wg.Add(1)
+			go func(i int, backend *storageBackend) {
+				defer wg.Done()
+				probeErrs[i] = backend.service.Probe(ctx)
+			}(i, backend)
+		}
+		wg.Wait()
+		storageCheck := gin.H{}
+		var failedBackends []string
+		for i, backend := range storageBackends {
+			details := gin.H{}
+			if backend.breaker != nil {
+				details["circuit"] = backend.breaker.Stats().State
+			}
+			storageCheck[backend.name] = check(probeErrs[i], details)
+			if probeErrs[i] != nil {
+				failedBackends = append(failedBackends, backend.name)
+			}
+		}
+
+		free, diskErr := utils.CheckDiskSpace(tempDir, minFreeDisk)
+		var diskCheck gin.H
+		if errors.Is(diskErr, errors.ErrUnsupported) {
+			diskCheck = gin.H{"status": "unknown"}
+		} else {
+			diskCheck = check(diskErr, gin.H{"freeBytes": free, "minFreeBytes": minFreeDisk})
+		}
+
+		checkpointCheck := check(utils.CheckWritable(checkpointDir), gin.H{})
+
+		status := http.StatusOK
+		overall := "ok"
+		if !ready {
+			status = http.StatusServiceUnavailable
+			overall = "unavailable"
+			logger.Printf("就绪检查失败: 不可用的存储后端 %v, 临时目录 %v, 断点续传目录 %v",
+				failedBackends, diskCheck["status"], checkpointCheck["status"])
+		}
+		c.JSON(status, gin.H{
+			"status": overall,
+			"time":   time.Now().Format(time.RFC3339),
+			"checks": gin.H{
+				"storage":    storageCheck,
+				"tempDisk":   diskCheck,
+				"checkpoint": checkpointCheck,
+			},
+		})
+	})
+
 	// 添加预签名下载URL接口
 	r.GET("/api/download/:filename", func(c *gin.Context) {
 		logger.Printf("收到预签名下载URL请求")
@@ -423,6 +885,12 @@ func main() {
 			expiration = maxExpiration
 		}
 
+		// 选择存储后端，未指定时使用上传时记录的后端或按对象名匹配路由规则
+		backendName, storageService, ok := locateStorageBackend(c, storageRouter, c.Query("backend"), fileName)
+		if !ok {
+			return
+		}
+
 		// 生成预签名下载URL
 		url, headers, err := storageService.GeneratePresignedDownloadURL(context.Background(), fileName, expiration)
 		if err != nil {
@@ -434,6 +902,11 @@ func main() {
 			return
 		}
 
+		// 代理下载链接可能是相对地址，按请求地址补全
+		if strings.HasPrefix(url, "/") {
+			url = requestBaseURL(c) + url
+		}
+
 		// 返回预签名下载URL和必要的请求头
 		c.JSON(http.StatusOK, gin.H{
 			"url":        url,
@@ -441,67 +914,225 @@ func main() {
 			"expiration": time.Now().Add(expiration).Format(time.RFC3339),
 			"method":     "GET",
 			"fileName":   fileName,
+			"backend":    backendName,
 		})
 	})
 
-	// 添加预签名URL接口
-	r.POST("/api/presign", func(c *gin.Context) {
-		logger.Printf("收到预签名URL请求")
-
-		// 获取文件名和文件大小
-		fileName := c.PostForm("fileName")
-		fileSizeStr := c.PostForm("fileSize")
-
-		if fileName == "" {
-			logger.Printf("文件名为空")
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "No file name provided",
+	// 代理下载：不支持预签名下载的存储后端（例如WebDAV）生成的下载链接，校验签名后由本服务读取对象并转发
+	r.GET("/api/proxy/:backend/*object", func(c *gin.Context) {
+		backendName := c.Param("backend")
+		objectName := strings.TrimPrefix(c.Param("object"), "/")
+		if err := proxySigner.Verify(backendName, objectName, c.Query("expires"), c.Query("signature")); err != nil {
+			logger.Printf("代理下载链接校验失败: %s/%s, %v", backendName, objectName, err)
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":  "Invalid or expired download link",
+				"detail": err.Error(),
 			})
 			return
 		}
-		logger.Printf("请求生成预签名URL的文件名: %s", fileName)
-
-		// 根据文件大小动态调整过期时间
-		expiration := 10 * time.Minute // 默认10分钟
 
-		// 如果提供了文件大小，则根据大小调整过期时间
-		if fileSizeStr != "" {
-			fileSize, err := strconv.ParseInt(fileSizeStr, 10, 64)
-			if err == nil {
-				// 100MB以上的文件
-				if fileSize > 100*1024*1024 {
-					expiration = 30 * time.Minute
-				}
-				// 500MB以上的文件
-				if fileSize > 500*1024*1024 {
-					expiration = 60 * time.Minute
-				}
-				// 1GB以上的文件
-				if fileSize > 1*1024*1024*1024 {
-					expiration = 3 * time.Hour
-				}
-				logger.Printf("文件大小: %d 字节, 设置过期时间: %v", fileSize, expiration)
-			}
+		storageService, err := storageRouter.Get(backendName)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":  "Storage backend not available",
+				"detail": err.Error(),
+			})
+			return
 		}
 
-		// 生成预签名URL
-		url, headers, err := storageService.GeneratePresignedURL(context.Background(), fileName, expiration)
+		reader, info, err := storageService.GetObject(c.Request.Context(), objectName)
+		if errors.Is(err, storage.ErrObjectNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":  "File not found",
+				"detail": err.Error(),
+			})
+			return
+		}
 		if err != nil {
-			logger.Printf("生成预签名URL失败: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":  "Failed to generate presigned URL",
+			logger.Printf("代理下载读取对象失败: %s/%s, %v", backendName, objectName, err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":  "Failed to read file from storage",
 				"detail": err.Error(),
 			})
 			return
 		}
+		defer reader.Close()
 
-		// 返回预签名URL和必要的请求头
-		c.JSON(http.StatusOK, gin.H{
-			"url":         url,
-			"headers":     headers,
-			"expiration":  time.Now().Add(expiration).Format(time.RFC3339),
-			"method":      "PUT",
-			"contentType": "application/octet-stream",
+		contentType := info.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(objectName)}))
+		if info.ETag != "" {
+			c.Header("ETag", "\""+info.ETag+"\"")
+		}
+		if !info.LastModified.IsZero() {
+			c.Header("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+		}
+		c.DataFromReader(http.StatusOK, info.Size, contentType, reader, nil)
+	})
+
+	// 将指定前缀下的所有对象打包为zip流式下载，不在服务端落盘
+	r.GET("/api/archive", func(c *gin.Context) {
+		logger.Printf("收到打包下载请求")
+
+		prefix := strings.TrimPrefix(c.Query("prefix"), "/")
+		if prefix == "" {
+			logger.Printf("打包前缀为空")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "No prefix provided",
+			})
+			return
+		}
+
+		// 压缩方式，store仅打包不压缩，适合已压缩的文件
+		var method uint16
+		switch c.DefaultQuery("method", "deflate") {
+		case "store":
+			method = zip.Store
+		case "deflate":
+			method = zip.Deflate
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid method, use store or deflate",
+			})
+			return
+		}
+
+		_, storageService, ok := locateStorageBackend(c, storageRouter, c.Query("backend"), prefix)
+		if !ok {
+			return
+		}
+
+		// zip内的路径相对于前缀的上一级目录，例如 photos/ 下的文件解压后直接位于根目录，
+		// 而 photos 作为前缀时保留 photos/ 目录
+		trimPrefix := prefix[:strings.LastIndex(prefix, "/")+1]
+		zipName := filepath.Base(strings.TrimSuffix(prefix, "/")) + ".zip"
+
+		// 按目录列出对象，避免 photos 同时匹配到 photos2/ 下的文件
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+
+		ctx := c.Request.Context()
+		objects, err := storageService.ListObjects(ctx, prefix)
+		if err != nil {
+			logger.Printf("列出对象失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":  "Failed to list objects",
+				"detail": err.Error(),
+			})
+			return
+		}
+		if len(objects) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "No objects found under prefix",
+			})
+			return
+		}
+
+		var totalSize int64
+		for _, obj := range objects {
+			totalSize += obj.Size
+		}
+		logger.Printf("开始打包: 前缀=%s, 文件数=%d, 总大小=%d 字节, 压缩方式=%d", prefix, len(objects), totalSize, method)
+
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": zipName}))
+		c.Status(http.StatusOK)
+
+		// 响应头已发送，出错时只能记录日志并中断连接
+		startTime := time.Now()
+		if err := storage.WriteZipArchive(ctx, c.Writer, storageService, objects, trimPrefix, method); err != nil {
+			if ctx.Err() != nil {
+				logger.Printf("客户端取消打包下载: %s, %v", prefix, ctx.Err())
+			} else {
+				logger.Printf("打包下载失败: %s, %v", prefix, err)
+			}
+			panic(http.ErrAbortHandler)
+		}
+		logger.Printf("打包下载完成: %s, 用时: %v", prefix, time.Since(startTime))
+	})
+
+	// 添加预签名URL接口
+	r.POST("/api/presign", func(c *gin.Context) {
+		logger.Printf("收到预签名URL请求")
+
+		// 获取文件名和文件大小
+		fileName := c.PostForm("fileName")
+		fileSizeStr := c.PostForm("fileSize")
+
+		if fileName == "" {
+			logger.Printf("文件名为空")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "No file name provided",
+			})
+			return
+		}
+		logger.Printf("请求生成预签名URL的文件名: %s", fileName)
+
+		// 根据文件大小动态调整过期时间
+		expiration := 10 * time.Minute // 默认10分钟
+
+		// 如果提供了文件大小，则根据大小调整过期时间
+		var fileSize int64
+		if fileSizeStr != "" {
+			var err error
+			fileSize, err = strconv.ParseInt(fileSizeStr, 10, 64)
+			if err == nil {
+				// 100MB以上的文件
+				if fileSize > 100*1024*1024 {
+					expiration = 30 * time.Minute
+				}
+				// 500MB以上的文件
+				if fileSize > 500*1024*1024 {
+					expiration = 60 * time.Minute
+				}
+				// 1GB以上的文件
+				if fileSize > 1*1024*1024*1024 {
+					expiration = 3 * time.Hour
+				}
+				logger.Printf("文件大小: %d 字节, 设置过期时间: %v", fileSize, expiration)
+			}
+		}
+
+		// 选择存储后端，未指定时按文件名和文件大小匹配路由规则
+		backendName, storageService, ok := resolveStorageBackend(c, storageRouter, c.PostForm("backend"), fileName, fileSize)
+		if !ok {
+			return
+		}
+
+		// 生成预签名URL
+		url, headers, err := storageService.GeneratePresignedURL(context.Background(), fileName, expiration)
+		if errors.Is(err, storage.ErrPresignNotSupported) {
+			logger.Printf("存储后端 %s 不支持预签名上传", backendName)
+			c.JSON(http.StatusNotImplemented, gin.H{
+				"error":   "Presigned upload is not supported by this storage backend, use /api/upload instead",
+				"detail":  err.Error(),
+				"backend": backendName,
+			})
+			return
+		}
+		if err != nil {
+			logger.Printf("生成预签名URL失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":  "Failed to generate presigned URL",
+				"detail": err.Error(),
+			})
+			return
+		}
+
+		// 预签名上传不经过本服务，生成链接时记录对象所在的后端
+		placeObject(storageRouter, fileName, backendName)
+
+		// 返回预签名URL和必要的请求头
+		c.JSON(http.StatusOK, gin.H{
+			"url":         url,
+			"headers":     headers,
+			"expiration":  time.Now().Add(expiration).Format(time.RFC3339),
+			"method":      "PUT",
+			"contentType": "application/octet-stream",
+			"backend":     backendName,
 		})
 	})
 
@@ -532,8 +1163,29 @@ func main() {
 			return
 		}
 
+		// 解析可选的访问控制和展示参数
+		opts, err := parseShortLinkOptions(c)
+		if err != nil {
+			logger.Printf("解析短链接参数失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		// 记录对象所在的存储后端，详情页需要从该后端查询文件信息
+		objectName := opts.ObjectName
+		if objectName == "" {
+			objectName = fileName
+		}
+		backendName, _, ok := locateStorageBackend(c, storageRouter, c.PostForm("backend"), objectName)
+		if !ok {
+			return
+		}
+		opts.Backend = backendName
+
 		// 生成短链接
-		encodedID, err := shortLinkManager.CreateShortLink(longURL, fileName, expiration)
+		encodedID, err := shortLinkManager.CreateShortLinkWithOptions(longURL, fileName, expiration, opts)
 		if err != nil {
 			logger.Printf("生成短链接失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -543,25 +1195,124 @@ func main() {
 		}
 
 		// 构建短链接URL (encodedID已经通过CreateShortLink进行了URL编码)
-		protocol := "http"
-		if c.Request.TLS != nil {
-			protocol = "https"
-		}
-		shortURL := fmt.Sprintf("%s://%s/s/%s", protocol, c.Request.Host, encodedID)
+		shortURL := fmt.Sprintf("%s/s/%s", requestBaseURL(c), encodedID)
 
 		logger.Printf("生成短链接成功: %s -> %s", shortURL, longURL)
 
 		// 返回短链接
+		maxDownloads := opts.MaxDownloads
+		if opts.OneTime {
+			maxDownloads = 1
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"shortURL":   shortURL,
-			"id":         encodedID,
-			"fileName":   fileName,
-			"expiration": expiration.Format(time.RFC3339),
+			"shortURL":          shortURL,
+			"id":                encodedID,
+			"fileName":          fileName,
+			"expiration":        expiration.Format(time.RFC3339),
+			"passwordProtected": opts.Password != "",
+			"maxDownloads":      maxDownloads,
+			"oneTime":           opts.OneTime,
+			"landingPage":       opts.LandingPage,
+			"qrCodeURL":         fmt.Sprintf("%s/api/short-link/%s/qrcode", requestBaseURL(c), strings.SplitN(encodedID, "/", 2)[0]),
+		})
+	})
+
+	// 创建包含多个文件的集合短链接，通过对象名列表或对象前缀指定文件
+	r.POST("/api/short-link/collection", func(c *gin.Context) {
+		logger.Printf("收到生成集合短链接请求")
+
+		name := c.PostForm("name")
+		expirationStr := c.PostForm("expiration")
+		objectNames := c.PostFormArray("objectNames")
+		prefix := c.PostForm("prefix")
+
+		if expirationStr == "" || (len(objectNames) == 0 && prefix == "") {
+			logger.Printf("缺少必要参数")
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Missing required parameters",
+			})
+			return
+		}
+		if len(objectNames) > 0 && prefix != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Specify either objectNames or prefix, not both",
+			})
+			return
+		}
+
+		// 解析过期时间
+		expiration, err := time.Parse(time.RFC3339, expirationStr)
+		if err != nil {
+			logger.Printf("解析过期时间失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid expiration format",
+			})
+			return
+		}
+
+		// 解析可选的访问控制参数
+		opts, err := parseShortLinkOptions(c)
+		if err != nil {
+			logger.Printf("解析短链接参数失败: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		// 前缀按目录匹配，photos 规范化为 photos/，避免集合包含 photos-private/ 下的文件
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		opts.Objects = objectNames
+		opts.Prefix = prefix
+
+		// 集合中的文件位于同一个存储后端，未指定时使用第一个对象记录的后端，或按前缀匹配路由规则
+		routeKey := prefix
+		if routeKey == "" {
+			routeKey = objectNames[0]
+		}
+		backendName, _, ok := locateStorageBackend(c, storageRouter, c.PostForm("backend"), routeKey)
+		if !ok {
+			return
+		}
+		opts.Backend = backendName
+
+		// 未指定集合名称时使用前缀的最后一级目录名
+		if name == "" {
+			name = filepath.Base(strings.TrimSuffix(prefix, "/"))
+			if prefix == "" || name == "." || name == "/" {
+				name = "files"
+			}
+		}
+
+		// 集合链接没有固定的长链接，下载时按需生成预签名URL
+		encodedID, err := shortLinkManager.CreateShortLinkWithOptions("", name, expiration, opts)
+		if err != nil {
+			logger.Printf("生成集合短链接失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to generate short link",
+			})
+			return
+		}
+
+		shortURL := fmt.Sprintf("%s/s/%s", requestBaseURL(c), encodedID)
+		logger.Printf("生成集合短链接成功: %s, 文件数: %d, 前缀: %s", shortURL, len(objectNames), prefix)
+
+		c.JSON(http.StatusOK, gin.H{
+			"shortURL":          shortURL,
+			"id":                encodedID,
+			"name":              name,
+			"objectNames":       objectNames,
+			"prefix":            prefix,
+			"expiration":        expiration.Format(time.RFC3339),
+			"passwordProtected": opts.Password != "",
+			"qrCodeURL":         fmt.Sprintf("%s/api/short-link/%s/qrcode", requestBaseURL(c), strings.SplitN(encodedID, "/", 2)[0]),
 		})
 	})
 
 	// 处理短链接访问 - 使用编码后的文件名作为路径参数
-	r.GET("/s/:uniqueID/*fileName", func(c *gin.Context) {
+	// GET用于直接访问，POST用于提交密码表单
+	shortLinkHandler := func(c *gin.Context) {
 		// 获取唯一ID
 		uniqueID := c.Param("uniqueID")
 
@@ -573,34 +1324,227 @@ func main() {
 
 		logger.Printf("收到短链接访问请求: ID=%s, 文件名=%s", uniqueID, fileName)
 
-		// 从唯一ID获取长URL
-		longURL, fileName, exists := shortLinkManager.GetLongURLByUniqueID(uniqueID)
+		// 脚本通过请求头传递密码，浏览器通过密码表单提交
+		password := c.GetHeader("X-Share-Password")
+		fromScript := password != ""
+		if password == "" && c.Request.Method == http.MethodPost {
+			password = c.PostForm("password")
+		}
 
-		// 如果找不到，尝试使用完整路径（兼容旧版格式）
-		if !exists {
-			completePath := uniqueID
-			if fileName != "" {
-				completePath = uniqueID + "/" + fileName
+		// 开启了详情页的链接先展示文件详情，点击下载按钮时带上download参数再走下载流程
+		// 受密码保护的详情页和集合页在密码校验通过前只展示密码输入页面，不透露文件信息
+		if c.Query("download") == "" {
+			link, exists := shortLinkManager.GetShortLink(uniqueID)
+			if !exists && fileName != "" {
+				link, exists = shortLinkManager.GetShortLink(uniqueID + "/" + fileName)
+			}
+			if exists && link.IsPasswordProtected() && (link.LandingPage || link.IsCollection()) {
+				verified, err := shortLinkManager.VerifyPassword(link.UniqueID, password, c.ClientIP())
+				if err != nil {
+					linkStatsManager.RecordAccess(uniqueID, utils.LinkAccess{
+						Time:      time.Now(),
+						ClientIP:  c.ClientIP(),
+						UserAgent: c.Request.UserAgent(),
+						Referer:   c.Request.Referer(),
+						Result:    shortLinkAccessResult(err),
+					})
+					respondShortLinkError(c, uniqueID, fileName, fromScript, err)
+					return
+				}
+				link = verified
+			} else if c.Request.Method != http.MethodGet {
+				// 不需要详情页密码的链接，表单提交直接走下载流程
+				exists = false
+			}
+			if exists && (link.LandingPage || link.IsCollection()) {
+				storageService, err := storageRouter.Get(link.Backend)
+				if err != nil {
+					logger.Printf("短链接的存储后端不可用: %s, %v", uniqueID, err)
+					c.JSON(http.StatusInternalServerError, gin.H{
+						"error":  "Storage backend not available",
+						"detail": err.Error(),
+					})
+					return
+				}
+				if link.IsCollection() {
+					renderShareCollection(c, storageService, link, password)
+				} else {
+					renderShareLanding(c, storageService, link, password)
+				}
+				linkStatsManager.RecordAccess(uniqueID, utils.LinkAccess{
+					Time:      time.Now(),
+					ClientIP:  c.ClientIP(),
+					UserAgent: c.Request.UserAgent(),
+					Referer:   c.Request.Referer(),
+					Result:    utils.AccessResultViewed,
+				})
+				return
 			}
+		}
+
+		// 校验密码并计入下载次数
+		link, err := shortLinkManager.AccessShortLink(uniqueID, password, c.ClientIP())
+
+		// 如果找不到，尝试使用完整路径（兼容旧版格式）
+		if errors.Is(err, utils.ErrLinkNotFound) && fileName != "" {
+			completePath := uniqueID + "/" + fileName
 			logger.Printf("尝试使用完整路径重新查找: %s", completePath)
-			longURL, fileName, exists = shortLinkManager.GetLongURL(completePath)
+			link, err = shortLinkManager.AccessShortLink(completePath, password, c.ClientIP())
+		}
+
+		// 记录本次访问
+		linkStatsManager.RecordAccess(uniqueID, utils.LinkAccess{
+			Time:      time.Now(),
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Referer:   c.Request.Referer(),
+			Result:    shortLinkAccessResult(err),
+		})
+
+		if err != nil {
+			respondShortLinkError(c, uniqueID, fileName, fromScript, err)
+			return
+		}
+
+		// 集合链接：下载全部文件的压缩包或集合中的单个文件
+		if link.IsCollection() {
+			storageService, err := storageRouter.Get(link.Backend)
+			if err != nil {
+				logger.Printf("短链接的存储后端不可用: %s, %v", uniqueID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":  "Storage backend not available",
+					"detail": err.Error(),
+				})
+				return
+			}
+
+			if c.Query("zip") != "" {
+				streamShareCollectionZip(c, storageService, link)
+				return
+			}
+
+			objectName := c.Query("file")
+			if objectName == "" || !link.ContainsObject(objectName) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "文件不在分享集合中",
+				})
+				return
+			}
+
+			// 预签名URL有效期不超过1小时，也不超过链接本身的有效期
+			expiration := time.Until(link.Expiration)
+			if expiration > time.Hour {
+				expiration = time.Hour
+			}
+			longURL, _, err := storageService.GeneratePresignedDownloadURL(c.Request.Context(), objectName, expiration)
+			if err != nil {
+				logger.Printf("生成预签名下载URL失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":  "Failed to generate presigned download URL",
+					"detail": err.Error(),
+				})
+				return
+			}
+			link.LongURL = longURL
+			link.FileName = filepath.Base(objectName)
+		}
+
+		logger.Printf("短链接重定向: %s -> %s, 已下载次数: %d", uniqueID, link.LongURL, link.Downloads)
+
+		// 设置Content-Disposition头以提示浏览器下载文件
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": link.FileName}))
+
+		// 重定向到长链接，表单提交后使用303以便浏览器改用GET请求
+		status := http.StatusTemporaryRedirect
+		if c.Request.Method == http.MethodPost {
+			status = http.StatusSeeOther
 		}
+		c.Redirect(status, link.LongURL)
+	}
+	r.GET("/s/:uniqueID/*fileName", shortLinkHandler)
+	r.POST("/s/:uniqueID/*fileName", shortLinkHandler)
+
+	// 生成短链接的二维码图片
+	r.GET("/api/short-link/:uniqueID/qrcode", func(c *gin.Context) {
+		uniqueID := c.Param("uniqueID")
 
+		link, exists := shortLinkManager.GetShortLink(uniqueID)
 		if !exists {
-			logger.Printf("短链接不存在或已过期: %s", uniqueID)
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": "链接不存在或已过期",
 			})
 			return
 		}
 
-		logger.Printf("短链接重定向: %s -> %s", uniqueID, longURL)
+		// 解析图片尺寸，默认256像素
+		size, err := strconv.Atoi(c.DefaultQuery("size", "256"))
+		if err != nil || size < 64 || size > 1024 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid size, must be between 64 and 1024",
+			})
+			return
+		}
 
-		// 设置Content-Disposition头以提示浏览器下载文件
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+		shortURL := fmt.Sprintf("%s/s/%s", requestBaseURL(c), link.ID)
+		png, err := qrcode.Encode(shortURL, qrcode.Medium, size)
+		if err != nil {
+			logger.Printf("生成二维码失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to generate QR code",
+			})
+			return
+		}
+
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.Data(http.StatusOK, "image/png", png)
+	})
+
+	// 获取短链接访问统计
+	r.GET("/api/short-link/:uniqueID/stats", func(c *gin.Context) {
+		uniqueID := c.Param("uniqueID")
+		logger.Printf("收到短链接访问统计请求: ID=%s", uniqueID)
+
+		// 解析最近访问记录数量，默认20条
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid limit, must be a non-negative integer",
+			})
+			return
+		}
+
+		link, linkExists := shortLinkManager.GetShortLink(uniqueID)
+		stats, statsExists := linkStatsManager.GetStats(uniqueID, limit)
+		if !linkExists && !statsExists {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "链接不存在或已过期",
+			})
+			return
+		}
+
+		// 链接从未被访问过时返回空统计
+		if !statsExists {
+			stats = &utils.LinkStats{
+				UniqueID: uniqueID,
+				Counters: map[string]int{},
+				Recent:   []utils.LinkAccess{},
+			}
+		}
+
+		response := gin.H{
+			"id":     uniqueID,
+			"active": linkExists,
+			"stats":  stats,
+		}
+		if linkExists {
+			response["fileName"] = link.FileName
+			response["expiration"] = link.Expiration.Format(time.RFC3339)
+			response["downloads"] = link.Downloads
+			response["remainingDownloads"] = link.RemainingDownloads()
+			response["passwordProtected"] = link.IsPasswordProtected()
+		}
 
-		// 重定向到长链接
-		c.Redirect(http.StatusTemporaryRedirect, longURL)
+		c.JSON(http.StatusOK, response)
 	})
 
 	// 启动定时任务清理过期短链接
@@ -611,13 +1555,857 @@ func main() {
 		for range ticker.C {
 			logger.Printf("开始清理过期短链接")
 			shortLinkManager.CleanupExpiredLinks()
-		}
-	}()
 
-	// 启动服务器
-	port := ":5050"
-	logger.Printf("启动HTTP服务器, 监听端口%s", port)
-	if err := r.Run(port); err != nil {
+			// 访问统计在最后一次访问30天后清理
+			linkStatsManager.CleanupStale(30 * 24 * time.Hour)
+
+			// 清理无人订阅且长时间未更新的上传进度
+			progressManager.CleanupStale(time.Hour)
+
+			// 清理一天内没有更新的批量上传会话
+			batchManager.CleanupStale(24 * time.Hour)
+
+			// 清理结束超过7天的异步上传任务
+			jobManager.CleanupStale(7 * 24 * time.Hour)
+
+			// 清理一小时内没有新上传的客户端IP限速器
+			bandwidthThrottle.CleanupIdle(time.Hour)
+
+			// 中止一天内没有进展的分片上传，释放存储服务中的分片空间
+			for _, backend := range storageBackends {
+				// 复制后端的副本作为独立的后端清理
+				if backend.breaker == nil {
+					continue
+				}
+				if aborted, err := backend.breaker.AbortStaleUploads(context.Background(), 24*time.Hour); err != nil {
+					logger.Printf("清理存储后端 %s 未完成的分片上传失败: %v", backend.name, err)
+				} else if aborted > 0 {
+					logger.Printf("已中止存储后端 %s 的%d个未完成的分片上传", backend.name, aborted)
+				}
+			}
+		}
+	}()
+
+	// 启动服务器
+	port := ":5050"
+	logger.Printf("启动HTTP服务器, 监听端口%s", port)
+	if err := r.Run(port); err != nil {
 		logger.Fatalf("启动服务器失败: %v", err)
 	}
 }
+
+// runMigrate 执行 migrate 子命令，将一个存储后端的对象复制到另一个存储后端，返回进程退出码
+// 例如: uploader migrate -from cn-oss -to onprem-minio -prefix images/ -workers 8
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	from := flags.String("from", "", "源存储后端名称")
+	to := flags.String("to", "", "目标存储后端名称")
+	prefix := flags.String("prefix", "", "只迁移该前缀下的对象")
+	workers := flags.Int("workers", 4, "并行复制的对象数")
+	compare := flags.String("compare", storage.CompareETag, "判断目标对象相同的方式: size, etag, hash")
+	dryRun := flags.Bool("dry-run", false, "只列出需要复制的对象，不实际复制")
+	journalPath := flags.String("journal", "", "迁移记录文件，默认为 ./checkpoint/migrate/<源>-<目标>.jsonl")
+	reportPath := flags.String("report", "", "迁移报告文件，默认为 ./logs/migrate-<源>-<目标>-<时间>.json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" || *to == "" || *from == *to {
+		logger.Printf("必须指定不同的源和目标存储后端")
+		flags.Usage()
+		return 2
+	}
+
+	storageRouter := newMigrateBackends()
+	src, err := storageRouter.Get(*from)
+	if err != nil {
+		logger.Printf("源存储后端不可用: %v, 可用的后端: %s", err, strings.Join(storageRouter.Names(), ","))
+		return 2
+	}
+	dst, err := storageRouter.Get(*to)
+	if err != nil {
+		logger.Printf("目标存储后端不可用: %v, 可用的后端: %s", err, strings.Join(storageRouter.Names(), ","))
+		return 2
+	}
+
+	opts := storage.MigrationOptions{
+		Prefix:  strings.TrimPrefix(*prefix, "/"),
+		Workers: *workers,
+		Compare: *compare,
+		DryRun:  *dryRun,
+		TempDir: "./temp/migrate",
+		Logf:    logger.Printf,
+	}
+
+	// 试运行不读写迁移记录，每次都重新比较
+	if !*dryRun {
+		if *journalPath == "" {
+			*journalPath = filepath.Join("./checkpoint/migrate", *from+"-"+*to+".jsonl")
+		}
+		journal, err := storage.OpenMigrationJournal(*journalPath)
+		if err != nil {
+			logger.Printf("%v", err)
+			return 1
+		}
+		defer journal.Close()
+		opts.Journal = journal
+		logger.Printf("迁移记录: %s, 已完成%d个对象", *journalPath, journal.Len())
+	}
+
+	// 中断时停止分配新对象，已完成的对象保存在迁移记录中，重新运行时跳过
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Printf("开始迁移: %s -> %s, 前缀: %q, 并行数: %d, 比较方式: %s, 试运行: %v",
+		*from, *to, opts.Prefix, opts.Workers, opts.Compare, opts.DryRun)
+	report, migrateErr := storage.Migrate(ctx, src, dst, opts)
+	if report == nil {
+		logger.Printf("迁移失败: %v", migrateErr)
+		return 1
+	}
+
+	if *reportPath == "" {
+		*reportPath = filepath.Join("./logs", fmt.Sprintf("migrate-%s-%s-%s.json", *from, *to, time.Now().Format("20060102-150405")))
+	}
+	data, err := json.MarshalIndent(struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		*storage.MigrationReport
+	}{*from, *to, report}, "", "  ")
+	if err == nil {
+		err = os.WriteFile(*reportPath, data, 0644)
+	}
+	if err != nil {
+		logger.Printf("保存迁移报告失败: %v", err)
+	}
+
+	for _, failure := range report.Failures {
+		logger.Printf("复制失败: %s, %s", failure.Key, failure.Error)
+	}
+	for _, mismatch := range report.Mismatches {
+		logger.Printf("校验不一致: %s, %s", mismatch.Key, mismatch.Reason)
+	}
+	logger.Printf("迁移报告: 共%d个对象, 复制%d个 (%d字节), 已相同%d个, 之前已完成%d个, 待复制%d个, 失败%d个, 校验通过%d个, 不一致%d个, 用时%v",
+		report.Total, report.Copied, report.Bytes, report.Skipped, report.Resumed, report.Planned,
+		report.Failed, report.Verified, len(report.Mismatches), report.FinishedAt.Sub(report.StartedAt).Round(time.Second))
+	logger.Printf("迁移报告已保存: %s", *reportPath)
+
+	if migrateErr != nil {
+		logger.Printf("迁移中断: %v, 重新运行相同的命令可以继续", migrateErr)
+		return 1
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// objectURL 返回对象的访问地址，存储桶域名不带协议时使用https
+func objectURL(storageService storage.StorageService, objectName string) string {
+	bucketDomain := strings.TrimSuffix(storageService.GetBucketDomain(), "/")
+	if !strings.Contains(bucketDomain, "://") {
+		bucketDomain = "https://" + bucketDomain
+	}
+	return bucketDomain + "/" + strings.TrimPrefix(objectName, "/")
+}
+
+// setupStorageBackends 按环境变量创建所有命名存储后端及其路由，配置错误时直接退出
+// STORAGE_BACKENDS 列出多个命名后端，未配置时只使用 --storage / OSS 指定的单个后端
+// 同时返回代理下载链接的签名器，供不支持预签名下载的后端（例如WebDAV）生成链接
+func setupStorageBackends() (*storage.Router, []*storageBackend, *storage.ProxyLinkSigner) {
+	storageRouter, storageBackends, proxySigner := newStorageBackends(true)
+
+	// 默认后端和路由规则，未指定后端的请求按对象名前缀或文件大小选择后端
+	if defaultBackend := os.Getenv("STORAGE_DEFAULT_BACKEND"); defaultBackend != "" {
+		if err := storageRouter.SetDefault(defaultBackend); err != nil {
+			logger.Fatalf("设置默认存储后端失败: %v", err)
+		}
+	}
+	routingRules, err := storage.ParseRoutingRules(os.Getenv("STORAGE_ROUTES"))
+	if err != nil {
+		logger.Fatalf("解析存储路由规则失败: %v", err)
+	}
+	if err := storageRouter.SetRules(routingRules); err != nil {
+		logger.Fatalf("设置存储路由规则失败: %v", err)
+	}
+	logger.Printf("默认存储后端: %s, 路由规则: %d条", storageRouter.DefaultName(), len(routingRules))
+
+	// 按大小路由或上传时指定了后端的对象记录所在的后端，读取请求未指定后端时按记录选择
+	placements, err := storage.OpenPlacementIndex("./checkpoint/placements.jsonl")
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	storageRouter.SetPlacements(placements)
+
+	return storageRouter, storageBackends, proxySigner
+}
+
+// newMigrateBackends 为迁移命令创建命名存储后端，配置错误时直接退出
+// 只创建普通后端，不创建复制后端：复制后端会启动后台复制协程和清理协程，
+// 与同时运行的服务共用复制记录目录，迁移命令退出时还会中断正在进行的后台复制
+func newMigrateBackends() *storage.Router {
+	storageRouter, _, _ := newStorageBackends(false)
+	return storageRouter
+}
+
+// newStorageBackends 按 STORAGE_BACKENDS 创建命名存储后端并加入路由，配置错误时直接退出
+// replicated 为false时跳过复制后端
+func newStorageBackends(replicated bool) (*storage.Router, []*storageBackend, *storage.ProxyLinkSigner) {
+	backendSpecs, err := storage.ParseBackendSpecs(os.Getenv("STORAGE_BACKENDS"))
+	if err != nil {
+		logger.Fatalf("解析存储后端列表失败: %v", err)
+	}
+	if len(backendSpecs) == 0 {
+		backendSpecs = []storage.BackendSpec{{Name: storageType, Type: storageType}}
+	}
+
+	// 所有后端共用重试和熔断配置，但各自独立统计
+	retryConfig, err := storage.LoadRetryConfigFromEnv()
+	if err != nil {
+		logger.Fatalf("加载存储服务重试配置失败: %v", err)
+	}
+	logger.Printf("存储服务重试配置: 最多尝试%d次, 初始等待%v, 最长等待%v",
+		retryConfig.MaxAttempts, retryConfig.InitialBackoff, retryConfig.MaxBackoff)
+	breakerConfig, err := storage.LoadBreakerConfigFromEnv()
+	if err != nil {
+		logger.Fatalf("加载存储服务熔断配置失败: %v", err)
+	}
+
+	proxySigner := newProxyLinkSigner()
+
+	storageRouter := storage.NewRouter()
+	var storageBackends []*storageBackend
+	// 复制后端引用其他命名后端，在其他后端创建之后再创建
+	sort.SliceStable(backendSpecs, func(i, j int) bool {
+		return backendSpecs[i].Type != storage.TypeReplicated && backendSpecs[j].Type == storage.TypeReplicated
+	})
+	for _, spec := range backendSpecs {
+		var backend *storageBackend
+		if spec.Type == storage.TypeReplicated {
+			if !replicated {
+				logger.Printf("跳过复制后端: %s, 请直接指定其中的副本", spec.Name)
+				continue
+			}
+			backend, err = newReplicatedBackend(spec, storageRouter)
+		} else {
+			backend, err = newStorageBackend(spec, retryConfig, breakerConfig, proxySigner)
+		}
+		if err != nil {
+			logger.Fatalf("初始化存储后端 %s 失败: %v", spec.Name, err)
+		}
+		if err := storageRouter.Add(spec.Name, spec.Type, backend.service); err != nil {
+			logger.Fatalf("初始化存储后端 %s 失败: %v", spec.Name, err)
+		}
+		storageBackends = append(storageBackends, backend)
+		logger.Printf("存储后端初始化成功: %s (%s)", spec.Name, spec.Type)
+	}
+
+	return storageRouter, storageBackends, proxySigner
+}
+
+// newProxyLinkSigner 创建代理下载链接的签名器
+// PROXY_LINK_SECRET 未配置时使用随机密钥，服务重启后之前生成的代理下载链接会失效
+// PUBLIC_BASE_URL 为本服务的外部访问地址，未配置时生成相对链接，由接口按请求地址补全
+func newProxyLinkSigner() *storage.ProxyLinkSigner {
+	secret := []byte(os.Getenv("PROXY_LINK_SECRET"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			logger.Fatalf("生成代理下载链接密钥失败: %v", err)
+		}
+		logger.Printf("未配置PROXY_LINK_SECRET，使用随机密钥，重启后代理下载链接会失效")
+	}
+	return storage.NewProxyLinkSigner(secret, os.Getenv("PUBLIC_BASE_URL"))
+}
+
+// storageBackend 命名存储后端，保留重试和熔断装饰器以便查询运行状态
+// 复制后端没有自己的重试和熔断，retry 和 breaker 为nil
+type storageBackend struct {
+	name    string
+	service storage.StorageService
+	retry   *storage.RetryingService
+	breaker *storage.CircuitBreaker
+}
+
+// newStorageBackend 加载配置并创建存储后端，依次添加重试和熔断：
+// 临时故障（超时、限流、5xx、连接重置）按指数退避重试，重试用尽后计入熔断器
+func newStorageBackend(spec storage.BackendSpec, retryConfig storage.RetryConfig, breakerConfig storage.BreakerConfig, proxySigner *storage.ProxyLinkSigner) (*storageBackend, error) {
+	logger.Printf("加载存储后端配置: %s, 存储类型: %s", spec.Name, spec.Type)
+	config, err := storage.LoadStorageConfig(spec.Type, spec.EnvPrefix)
+	if err != nil {
+		return nil, err
+	}
+	service, err := storage.CreateStorageService(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// 不支持预签名下载的存储服务，下载链接指向本服务的代理下载接口
+	if downloader, ok := service.(storage.ProxyDownloader); ok {
+		downloader.SetProxyURLFunc(func(objectName string, expiration time.Duration) (string, error) {
+			return proxySigner.URL(spec.Name, objectName, expiration), nil
+		})
+	}
+
+	retry := storage.NewRetryingService(service, retryConfig, func(event storage.RetryEvent) {
+		logger.Printf("存储后端 %s 操作失败，%v后重试: %s 第%d次尝试, %v", spec.Name, event.Delay, event.Operation, event.Attempt, event.Err)
+	})
+	breaker := storage.NewCircuitBreaker(retry, breakerConfig, func(from, to string, err error) {
+		if err != nil {
+			logger.Printf("存储后端 %s 熔断状态变化: %s -> %s, %v", spec.Name, from, to, err)
+		} else {
+			logger.Printf("存储后端 %s 熔断状态变化: %s -> %s", spec.Name, from, to)
+		}
+	})
+
+	return &storageBackend{name: spec.Name, service: breaker, retry: retry, breaker: breaker}, nil
+}
+
+// newReplicatedBackend 创建复制后端，主副本和其他副本引用已经创建的命名后端
+func newReplicatedBackend(spec storage.BackendSpec, router *storage.Router) (*storageBackend, error) {
+	prefix := spec.EnvPrefix
+	if prefix == "" {
+		prefix = "REPLICATION"
+	}
+	config, err := storage.LoadReplicationConfigFromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	replica := func(name string) (storage.Replica, error) {
+		service, err := router.Get(name)
+		if err != nil {
+			return storage.Replica{}, err
+		}
+		return storage.Replica{Name: name, Service: service}, nil
+	}
+	primary, err := replica(config.Primary)
+	if err != nil {
+		return nil, err
+	}
+	secondaries := make([]storage.Replica, 0, len(config.Secondaries))
+	for _, name := range config.Secondaries {
+		secondary, err := replica(name)
+		if err != nil {
+			return nil, err
+		}
+		secondaries = append(secondaries, secondary)
+	}
+
+	service, err := storage.NewReplicatedService(spec.Name, primary, secondaries, config, func(format string, args ...interface{}) {
+		logger.Printf("复制后端 %s: "+format, append([]interface{}{spec.Name}, args...)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("复制后端 %s: 主副本 %s, 其他副本 %s, 复制方式 %s",
+		spec.Name, config.Primary, strings.Join(config.Secondaries, ","), config.Mode)
+
+	return &storageBackend{name: spec.Name, service: service}, nil
+}
+
+// resolveStorageBackend 按请求指定的后端名称选择上传使用的存储后端，未指定时按对象名和文件大小匹配路由规则
+// 后端不存在时返回400并返回false
+func resolveStorageBackend(c *gin.Context, router *storage.Router, name, objectName string, size int64) (string, storage.StorageService, bool) {
+	backendName, service, err := router.Resolve(name, objectName, size)
+	if err != nil {
+		logger.Printf("选择存储后端失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Unknown storage backend",
+			"detail":   err.Error(),
+			"backends": router.Names(),
+		})
+		return "", nil, false
+	}
+	return backendName, service, true
+}
+
+// locateStorageBackend 按请求指定的后端名称选择读取已有对象的存储后端，
+// 未指定时使用上传时记录的后端，没有记录时按对象名匹配路由规则，后端不存在时返回400并返回false
+func locateStorageBackend(c *gin.Context, router *storage.Router, name, objectName string) (string, storage.StorageService, bool) {
+	backendName, service, err := router.Locate(name, objectName)
+	if err != nil {
+		logger.Printf("选择存储后端失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Unknown storage backend",
+			"detail":   err.Error(),
+			"backends": router.Names(),
+		})
+		return "", nil, false
+	}
+	return backendName, service, true
+}
+
+// placeObject 记录对象上传到的存储后端，记录失败只影响未指定后端的读取请求，不中断上传
+func placeObject(router *storage.Router, objectName, backendName string) {
+	if err := router.Place(objectName, backendName); err != nil {
+		logger.Printf("记录对象所在的存储后端失败: %s, %v", objectName, err)
+	}
+}
+
+// uploadRateLimiter 创建一次上传使用的带宽限速器，没有任何限速生效时返回nil
+// 不能直接把nil的*utils.UploadLimiter赋给接口，否则接口不为nil，限速读取器仍会逐块等待
+func uploadRateLimiter(clientIP string, requestedKBps int) storage.RateLimiter {
+	limiter := bandwidthThrottle.Limiter(clientIP, requestedKBps)
+	if limiter == nil {
+		return nil
+	}
+	return limiter
+}
+
+// transferError 向存储服务上传失败时返回给客户端的状态码和错误信息
+type transferError struct {
+	status  int
+	message string
+	err     error
+}
+
+// transferToStorage 将临时目录中的文件上传到存储服务并校验，完成后删除临时文件并推送终止进度
+// 暂停会中断当前的上传尝试，恢复后重新上传：阿里云OSS借助断点续传记录从已完成的分片继续。
+// limiter 限制向存储服务上传的带宽；background为true时（异步任务）上传队列已满不会失败，而是等待后重新排队
+func transferToStorage(storageService storage.StorageService, upload *utils.TrackedUpload, limiter storage.RateLimiter, uploadID, objectName, tempFilePath string, size int64, background bool) (gin.H, *transferError) {
+	// 创建进度回调函数
+	progressCallback := func(increment, transferred, total int64) {
+		progressManager.UpdateProgress(uploadID, objectName, increment, transferred, total)
+
+		if verbose && (transferred == total || transferred%(total/10) < increment) {
+			percentage := int(float64(transferred) / float64(total) * 100)
+			logger.Printf("上传进度: %s - %d%% (%d/%d 字节)", objectName, percentage, transferred, total)
+		}
+	}
+
+	// 上传文件到存储服务
+	logger.Printf("开始上传文件到存储服务...")
+	startTime := time.Now()
+	var result interface{}
+	var err error
+	for {
+		if err = upload.WaitResumed(); err != nil {
+			break
+		}
+		// 等待上传调度器分配执行资格，排队期间推送队列位置
+		attemptCtx, cancelAttempt := upload.BeginAttempt()
+		parts, release, acquireErr := uploadScheduler.Acquire(attemptCtx, func(position int) {
+			progressManager.SetQueued(uploadID, objectName, position)
+		})
+		if acquireErr != nil {
+			cancelAttempt()
+			if errors.Is(acquireErr, utils.ErrQueueFull) {
+				if background {
+					logger.Printf("上传队列已满，稍后重新排队: %s", objectName)
+					select {
+					case <-time.After(uploadScheduler.RetryAfter()):
+					case <-upload.Context().Done():
+					}
+					continue
+				}
+				logger.Printf("上传队列已满: %s", objectName)
+				progressManager.Fail(uploadID, objectName, acquireErr)
+				if err := os.Remove(tempFilePath); err != nil {
+					logger.Printf("删除临时文件失败: %v", err)
+				}
+				return nil, &transferError{http.StatusTooManyRequests, "Upload queue is full", acquireErr}
+			}
+			err = acquireErr
+			if upload.Err() == nil && upload.Paused() {
+				logger.Printf("上传已暂停: %s", objectName)
+				progressManager.SetPhase(uploadID, objectName, utils.PhasePaused)
+				continue
+			}
+			break
+		}
+		progressManager.SetPhase(uploadID, objectName, utils.PhaseUploading)
+		uploadCtx := storage.WithRateLimiter(storage.WithPartParallelism(attemptCtx, parts), limiter)
+		result, err = storageService.UploadFile(uploadCtx, objectName, tempFilePath, progressCallback)
+		release()
+		cancelAttempt()
+		if err != nil && upload.Err() == nil && upload.Paused() {
+			logger.Printf("上传已暂停: %s", objectName)
+			progressManager.SetPhase(uploadID, objectName, utils.PhasePaused)
+			continue
+		}
+		break
+	}
+	if cancelErr := upload.Err(); cancelErr != nil {
+		logger.Printf("上传已取消: %s, %v", objectName, cancelErr)
+		if err := os.Remove(tempFilePath); err != nil {
+			logger.Printf("删除临时文件失败: %v", err)
+		}
+		progressManager.Fail(uploadID, objectName, cancelErr)
+		return nil, &transferError{http.StatusConflict, "Upload canceled", cancelErr}
+	}
+	if err != nil {
+		logger.Printf("上传文件到存储服务失败: %v", err)
+		progressManager.Fail(uploadID, objectName, err)
+		if errors.Is(err, storage.ErrCircuitOpen) {
+			return nil, &transferError{http.StatusServiceUnavailable, "Storage service unavailable", err}
+		}
+		return nil, &transferError{http.StatusInternalServerError, "Failed to upload file", err}
+	}
+	elapsedTime := time.Since(startTime)
+	logger.Printf("文件上传完成: %s, 用时: %v", objectName, elapsedTime)
+
+	// 校验存储服务中的对象大小与上传的文件一致
+	progressManager.SetPhase(uploadID, objectName, utils.PhaseVerifying)
+	if info, err := storageService.StatObject(upload.Context(), objectName); err != nil {
+		logger.Printf("校验上传文件失败，跳过校验: %v", err)
+	} else if info.Size != size {
+		verifyErr := fmt.Errorf("size mismatch: local %d, storage %d", size, info.Size)
+		logger.Printf("上传文件大小不一致: %s, %v", objectName, verifyErr)
+		if err := os.Remove(tempFilePath); err != nil {
+			logger.Printf("删除临时文件失败: %v", err)
+		}
+		progressManager.Fail(uploadID, objectName, verifyErr)
+		return nil, &transferError{http.StatusBadGateway, "Upload verification failed", verifyErr}
+	}
+
+	// 删除临时文件
+	logger.Printf("删除临时文件: %s", tempFilePath)
+	if err := os.Remove(tempFilePath); err != nil {
+		logger.Printf("删除临时文件失败: %v", err)
+	}
+
+	// 返回上传结果
+	url := objectURL(storageService, objectName)
+	logger.Printf("上传成功, 文件URL: %s", url)
+	response := gin.H{
+		"message":  "File uploaded successfully",
+		"filename": objectName,
+		"size":     size,
+		"url":      url,
+		"result":   result,
+	}
+	progressManager.Complete(uploadID, objectName, response)
+	return response, nil
+}
+
+// runUploadJob 在后台执行异步上传任务，并把结果保存到任务中，上传成功时记录对象所在的后端
+func runUploadJob(router *storage.Router, storageService storage.StorageService, job *utils.UploadJob, upload *utils.TrackedUpload) {
+	defer uploadTracker.Unregister(job.UploadID)
+
+	if err := jobManager.UpdateJob(job.ID, func(j *utils.UploadJob) {
+		j.State = utils.JobRunning
+		j.Attempts++
+	}); err != nil {
+		logger.Printf("更新异步上传任务失败: %s, %v", job.ID, err)
+	}
+
+	limiter := uploadRateLimiter(job.ClientIP, job.RateLimit)
+	response, transferErr := transferToStorage(storageService, upload, limiter, job.UploadID, job.ObjectName, job.LocalFile, job.Size, true)
+	if err := jobManager.UpdateJob(job.ID, func(j *utils.UploadJob) {
+		if transferErr != nil {
+			j.State = utils.JobFailed
+			j.Error = transferErr.err.Error()
+			return
+		}
+		j.State = utils.JobSucceeded
+		j.URL, _ = response["url"].(string)
+		j.Result = response["result"]
+	}); err != nil {
+		logger.Printf("更新异步上传任务失败: %s, %v", job.ID, err)
+	}
+	if transferErr != nil {
+		logger.Printf("异步上传任务失败: %s, %v", job.ID, transferErr.err)
+		return
+	}
+	placeObject(router, job.ObjectName, job.Backend)
+	logger.Printf("异步上传任务完成: %s", job.ID)
+}
+
+// resumeUploadJobs 恢复服务重启前未完成的异步上传任务
+// 临时文件仍保存在临时目录中，分片上传会借助断点续传记录从已完成的分片继续上传
+func resumeUploadJobs(router *storage.Router) {
+	for _, job := range jobManager.UnfinishedJobs() {
+		storageService, err := router.Get(job.Backend)
+		if err != nil {
+			// 存储后端已从配置中移除，任务无法继续
+			logger.Printf("恢复异步上传任务失败: %s, %v", job.ID, err)
+			if err := jobManager.UpdateJob(job.ID, func(j *utils.UploadJob) {
+				j.State = utils.JobFailed
+				j.Error = err.Error()
+			}); err != nil {
+				logger.Printf("更新异步上传任务失败: %s, %v", job.ID, err)
+			}
+			continue
+		}
+
+		upload, err := uploadTracker.Register(context.Background(), job.UploadID)
+		if err != nil {
+			logger.Printf("恢复异步上传任务失败: %s, %v", job.ID, err)
+			continue
+		}
+		logger.Printf("恢复异步上传任务: %s, 文件: %s", job.ID, job.ObjectName)
+		go runUploadJob(router, storageService, job, upload)
+	}
+}
+
+// rejectQueueFull 返回429并通过Retry-After告知客户端重试间隔
+func rejectQueueFull(c *gin.Context) {
+	c.Header("Retry-After", strconv.Itoa(int(uploadScheduler.RetryAfter().Seconds())))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":  "Upload queue is full",
+		"detail": utils.ErrQueueFull.Error(),
+	})
+}
+
+// controlUpload 对正在进行的上传执行取消、暂停或恢复操作
+func controlUpload(id string, action string) error {
+	if err := uploadTracker.Control(id, action); err != nil {
+		return err
+	}
+	logger.Printf("上传控制已生效: ID=%s, 操作=%s", id, action)
+
+	// 暂停立即通知订阅者；恢复后由新的上传进度切换回uploading阶段
+	if action == utils.ActionPause {
+		progressManager.SetPhase(id, "", utils.PhasePaused)
+	}
+	return nil
+}
+
+// requestBaseURL 根据请求推断服务的访问地址，例如 https://example.com
+func requestBaseURL(c *gin.Context) string {
+	protocol := "http"
+	if c.Request.TLS != nil {
+		protocol = "https"
+	}
+	return fmt.Sprintf("%s://%s", protocol, c.Request.Host)
+}
+
+// shortLinkAccessResult 将短链接访问的错误转换为访问统计中的结果
+func shortLinkAccessResult(err error) string {
+	switch {
+	case err == nil:
+		return utils.AccessResultRedirected
+	case errors.Is(err, utils.ErrLinkExpired):
+		return utils.AccessResultExpired
+	case errors.Is(err, utils.ErrLinkExhausted):
+		return utils.AccessResultExhausted
+	case errors.Is(err, utils.ErrPasswordRequired):
+		return utils.AccessResultPasswordRequired
+	case errors.Is(err, utils.ErrPasswordIncorrect):
+		return utils.AccessResultPasswordIncorrect
+	case errors.Is(err, utils.ErrTooManyAttempts):
+		return utils.AccessResultTooManyAttempts
+	default:
+		return utils.AccessResultNotFound
+	}
+}
+
+// respondShortLinkError 返回短链接访问失败的响应，浏览器访问需要密码的链接时渲染密码输入页面
+func respondShortLinkError(c *gin.Context, uniqueID, fileName string, fromScript bool, err error) {
+	switch {
+	case errors.Is(err, utils.ErrLinkExhausted):
+		logger.Printf("短链接下载次数已用完: %s", uniqueID)
+		c.JSON(http.StatusGone, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, utils.ErrPasswordRequired), errors.Is(err, utils.ErrPasswordIncorrect), errors.Is(err, utils.ErrTooManyAttempts):
+		logger.Printf("短链接密码校验未通过: %s, %v", uniqueID, err)
+		status := http.StatusUnauthorized
+		switch {
+		case errors.Is(err, utils.ErrPasswordIncorrect):
+			status = http.StatusForbidden
+		case errors.Is(err, utils.ErrTooManyAttempts):
+			status = http.StatusTooManyRequests
+		}
+
+		// 浏览器访问时渲染密码输入页面
+		if !fromScript && strings.Contains(c.GetHeader("Accept"), "text/html") {
+			errMsg := ""
+			if !errors.Is(err, utils.ErrPasswordRequired) {
+				errMsg = err.Error()
+			}
+			c.HTML(status, "share_password.html", gin.H{
+				"FileName": fileName,
+				"Action":   c.Request.URL.RequestURI(),
+				"Error":    errMsg,
+			})
+			return
+		}
+
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		logger.Printf("短链接不存在或已过期: %s", uniqueID)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "链接不存在或已过期",
+		})
+	}
+}
+
+// renderShareLanding 渲染短链接的文件详情页，文件大小和类型从存储服务查询
+// 受密码保护的链接只在密码校验通过后渲染，password随下载表单提交，避免再次输入
+func renderShareLanding(c *gin.Context, storageService storage.StorageService, link *utils.ShortLink, password string) {
+	baseURL := requestBaseURL(c)
+	shareURL := fmt.Sprintf("%s/s/%s", baseURL, link.ID)
+	expiration := link.Expiration.Format("2006-01-02 15:04:05")
+
+	// 查询文件元数据，失败时仍然展示页面，只是不显示大小和类型
+	var size, contentType string
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+	if info, err := storageService.StatObject(ctx, link.ObjectName); err != nil {
+		logger.Printf("获取文件元数据失败: %s, %v", link.ObjectName, err)
+	} else {
+		size = formatFileSize(info.Size)
+		contentType = info.ContentType
+	}
+
+	description := "有效期至 " + expiration
+	if size != "" {
+		description = size + "，" + description
+	}
+
+	c.HTML(http.StatusOK, "share_landing.html", gin.H{
+		"FileName":           link.FileName,
+		"Size":               size,
+		"ContentType":        contentType,
+		"Expiration":         expiration,
+		"RemainingDownloads": link.RemainingDownloads(),
+		"Password":           password,
+		"Description":        description,
+		"ShareURL":           shareURL,
+		"DownloadURL":        shareURL + "?download=1",
+		"QRCodeURL":          fmt.Sprintf("%s/api/short-link/%s/qrcode", baseURL, link.UniqueID),
+	})
+}
+
+// parseShortLinkOptions 从表单中解析短链接的可选访问控制和展示参数
+func parseShortLinkOptions(c *gin.Context) (utils.ShortLinkOptions, error) {
+	opts := utils.ShortLinkOptions{
+		Password:    c.PostForm("password"),
+		OneTime:     c.PostForm("oneTime") == "true",
+		ObjectName:  c.PostForm("objectName"),
+		LandingPage: c.PostForm("landingPage") == "true",
+	}
+
+	if maxDownloadsStr := c.PostForm("maxDownloads"); maxDownloadsStr != "" {
+		maxDownloads, err := strconv.Atoi(maxDownloadsStr)
+		if err != nil || maxDownloads < 0 {
+			return opts, errors.New("Invalid maxDownloads, must be a non-negative integer")
+		}
+		opts.MaxDownloads = maxDownloads
+	}
+
+	return opts, nil
+}
+
+// resolveCollectionObjects 获取集合链接包含的对象及其元数据
+// 前缀集合在访问时实时列出，对象列表集合逐个查询元数据并跳过已不存在的对象
+func resolveCollectionObjects(ctx context.Context, storageService storage.StorageService, link *utils.ShortLink) ([]storage.ObjectInfo, error) {
+	if link.Prefix != "" {
+		return storageService.ListObjects(ctx, link.CollectionPrefix())
+	}
+
+	objects := make([]storage.ObjectInfo, 0, len(link.Objects))
+	for _, objectName := range link.Objects {
+		info, err := storageService.StatObject(ctx, objectName)
+		if errors.Is(err, storage.ErrObjectNotExists) {
+			logger.Printf("集合中的文件已不存在: %s", objectName)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, *info)
+	}
+
+	return objects, nil
+}
+
+// renderShareCollection 渲染集合链接的文件列表页
+// 受密码保护的集合只在密码校验通过后渲染，password随下载表单提交
+func renderShareCollection(c *gin.Context, storageService storage.StorageService, link *utils.ShortLink, password string) {
+	baseURL := requestBaseURL(c)
+	shareURL := fmt.Sprintf("%s/s/%s", baseURL, link.ID)
+	expiration := link.Expiration.Format("2006-01-02 15:04:05")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	objects, err := resolveCollectionObjects(ctx, storageService, link)
+	if err != nil {
+		logger.Printf("获取集合文件列表失败: %s, %v", link.UniqueID, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  "Failed to list shared files",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	type fileEntry struct {
+		Name        string
+		Size        string
+		DownloadURL string
+	}
+	files := make([]fileEntry, 0, len(objects))
+	var totalSize int64
+	for _, obj := range objects {
+		name := strings.TrimPrefix(strings.TrimPrefix(obj.Key, link.CollectionPrefix()), "/")
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		totalSize += obj.Size
+		files = append(files, fileEntry{
+			Name:        name,
+			Size:        formatFileSize(obj.Size),
+			DownloadURL: shareURL + "?download=1&file=" + url.QueryEscape(obj.Key),
+		})
+	}
+
+	c.HTML(http.StatusOK, "share_collection.html", gin.H{
+		"Name":        link.FileName,
+		"Files":       files,
+		"TotalSize":   formatFileSize(totalSize),
+		"Expiration":  expiration,
+		"Password":    password,
+		"Description": fmt.Sprintf("%d 个文件，共 %s，有效期至 %s", len(files), formatFileSize(totalSize), expiration),
+		"ShareURL":    shareURL,
+		"ZipURL":      shareURL + "?download=1&zip=1",
+		"QRCodeURL":   fmt.Sprintf("%s/api/short-link/%s/qrcode", baseURL, link.UniqueID),
+	})
+}
+
+// streamShareCollectionZip 将集合链接中的所有文件打包为zip流式返回
+func streamShareCollectionZip(c *gin.Context, storageService storage.StorageService, link *utils.ShortLink) {
+	ctx := c.Request.Context()
+	objects, err := resolveCollectionObjects(ctx, storageService, link)
+	if err != nil {
+		logger.Printf("获取集合文件列表失败: %s, %v", link.UniqueID, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  "Failed to list shared files",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	zipName := link.FileName
+	if !strings.HasSuffix(strings.ToLower(zipName), ".zip") {
+		zipName += ".zip"
+	}
+
+	logger.Printf("开始打包集合: %s, 文件数: %d", link.UniqueID, len(objects))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": zipName}))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，出错时只能记录日志并中断连接
+	if err := storage.WriteZipArchive(ctx, c.Writer, storageService, objects, link.CollectionPrefix(), zip.Deflate); err != nil {
+		logger.Printf("打包集合失败: %s, %v", link.UniqueID, err)
+		panic(http.ErrAbortHandler)
+	}
+	logger.Printf("集合打包完成: %s", link.UniqueID)
+}
+
+// formatFileSize 将字节数格式化为易读的文件大小
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
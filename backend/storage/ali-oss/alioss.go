@@ -2,8 +2,12 @@ package alioss
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
@@ -18,6 +22,7 @@ type AliOSSConfig struct {
 	AccessKeySecret string
 	Region          string
 	BucketName      string
+	Multipart       storage.MultipartConfig
 }
 
 // GetType 返回存储类型标识
@@ -31,13 +36,14 @@ func (c *AliOSSConfig) Validate() error {
 		c.Region == "" || c.BucketName == "" {
 		return fmt.Errorf("缺少必要的OSS配置")
 	}
-	return nil
+	return c.Multipart.Validate()
 }
 
 // AliOSSService 阿里云OSS存储服务实现
 type AliOSSService struct {
 	client   *oss.Client
 	uploader *oss.Uploader
+	tuner    *storage.ParallelismTuner
 	config   *AliOSSConfig
 }
 
@@ -57,29 +63,32 @@ func NewAliOSSService(config *AliOSSConfig) (*AliOSSService, error) {
 	client := oss.NewClient(cfg)
 
 	// 创建上传管理器
-	uploader := createUploader(client)
+	uploader := createUploader(client, config.Multipart)
 
 	return &AliOSSService{
 		client:   client,
 		uploader: uploader,
+		tuner:    storage.NewParallelismTuner(config.Multipart),
 		config:   config,
 	}, nil
 }
 
-// 创建上传管理器
-func createUploader(client *oss.Client) *oss.Uploader {
-	return client.NewUploader(func(uo *oss.UploaderOptions) {
-		uo.PartSize = 5 * 1024 * 1024      // 设置分片大小为5MB
-		uo.ParallelNum = 3                 // 设置并行上传数为3
-		uo.EnableCheckpoint = true         // 启用断点续传
-		uo.CheckpointDir = "./checkpoint/" // 断点记录文件保存路径
+// 创建上传管理器，上传请求经过限速包装
+// 分片大小和并行数为默认值，每次上传时按文件大小和吞吐量覆盖
+func createUploader(client *oss.Client, multipart storage.MultipartConfig) *oss.Uploader {
+	return oss.NewUploader(&throttledClient{client}, func(uo *oss.UploaderOptions) {
+		uo.PartSize = multipart.PartSize       // 设置分片大小
+		uo.ParallelNum = multipart.ParallelNum // 设置并行上传数
+		uo.EnableCheckpoint = true             // 启用断点续传
+		uo.CheckpointDir = "./checkpoint/"     // 断点记录文件保存路径
 	})
 }
 
 // UploadFile 上传文件到OSS
 func (s *AliOSSService) UploadFile(ctx context.Context, objectName string, localFile string, progressFn storage.ProgressCallback) (interface{}, error) {
 	// 检查文件是否存在和可访问
-	if _, err := os.Stat(localFile); err != nil {
+	fileInfo, err := os.Stat(localFile)
+	if err != nil {
 		return nil, fmt.Errorf("文件访问错误: %v", err)
 	}
 
@@ -99,11 +108,21 @@ func (s *AliOSSService) UploadFile(ctx context.Context, objectName string, local
 		ProgressFn:   progressCallback,
 	}
 
+	// 按文件大小选择分片大小，并行数不超过上传调度器分配的配额
+	partSize := s.config.Multipart.PartSizeFor(fileInfo.Size())
+	parallelism := s.tuner.Parallelism(storage.PartParallelism(ctx))
+	optFn := func(uo *oss.UploaderOptions) {
+		uo.PartSize = partSize
+		uo.ParallelNum = parallelism
+	}
+
 	// 使用Uploader上传文件
-	result, err := s.uploader.UploadFile(ctx, putRequest, localFile)
+	startTime := time.Now()
+	result, err := s.uploader.UploadFile(ctx, putRequest, localFile, optFn)
 	if err != nil {
 		return nil, err
 	}
+	s.tuner.Observe(fileInfo.Size(), partSize, parallelism, time.Since(startTime))
 
 	return result, nil
 }
@@ -119,6 +138,94 @@ func (s *AliOSSService) IsObjectExist(ctx context.Context, objectName string) (b
 	return exists, nil
 }
 
+// StatObject 获取OSS对象的元数据
+func (s *AliOSSService) StatObject(ctx context.Context, objectName string) (*storage.ObjectInfo, error) {
+	result, err := s.client.HeadObject(ctx, &oss.HeadObjectRequest{
+		Bucket: oss.Ptr(s.config.BucketName),
+		Key:    oss.Ptr(objectName),
+	})
+	if err != nil {
+		var serviceErr *oss.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.StatusCode == http.StatusNotFound {
+			return nil, storage.ErrObjectNotExists
+		}
+		return nil, fmt.Errorf("获取对象元数据失败: %w", err)
+	}
+
+	info := &storage.ObjectInfo{
+		Key:  objectName,
+		Size: result.ContentLength,
+	}
+	if result.ContentType != nil {
+		info.ContentType = *result.ContentType
+	}
+	if result.ETag != nil {
+		info.ETag = strings.Trim(*result.ETag, "\"")
+	}
+	if result.LastModified != nil {
+		info.LastModified = *result.LastModified
+	}
+
+	return info, nil
+}
+
+// ListObjects 列出OSS中指定前缀下的所有对象
+func (s *AliOSSService) ListObjects(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	paginator := s.client.NewListObjectsV2Paginator(&oss.ListObjectsV2Request{
+		Bucket: oss.Ptr(s.config.BucketName),
+		Prefix: oss.Ptr(prefix),
+	})
+
+	var objects []storage.ObjectInfo
+	for paginator.HasNext() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("列出对象失败: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			info := storage.ObjectInfo{
+				Key:  oss.ToString(obj.Key),
+				Size: obj.Size,
+				ETag: strings.Trim(oss.ToString(obj.ETag), "\""),
+			}
+			if obj.LastModified != nil {
+				info.LastModified = *obj.LastModified
+			}
+			objects = append(objects, info)
+		}
+	}
+
+	return objects, nil
+}
+
+// GetObject 读取OSS对象内容
+func (s *AliOSSService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	result, err := s.client.GetObject(ctx, &oss.GetObjectRequest{
+		Bucket: oss.Ptr(s.config.BucketName),
+		Key:    oss.Ptr(objectName),
+	})
+	if err != nil {
+		var serviceErr *oss.ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.StatusCode == http.StatusNotFound {
+			return nil, nil, storage.ErrObjectNotExists
+		}
+		return nil, nil, fmt.Errorf("读取对象失败: %w", err)
+	}
+
+	info := &storage.ObjectInfo{
+		Key:         objectName,
+		Size:        result.ContentLength,
+		ContentType: oss.ToString(result.ContentType),
+		ETag:        strings.Trim(oss.ToString(result.ETag), "\""),
+	}
+	if result.LastModified != nil {
+		info.LastModified = *result.LastModified
+	}
+
+	return result.Body, info, nil
+}
+
 // GeneratePresignedURL 生成预签名上传URL
 func (s *AliOSSService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
 	// 创建上传对象的请求
@@ -160,6 +267,18 @@ func (s *AliOSSService) GetBucketDomain() string {
 	return fmt.Sprintf("%s.%s.aliyuncs.com", s.config.BucketName, s.config.Region)
 }
 
+// Probe 列出存储桶中的一个对象，检查网络、凭证和存储桶是否可用
+func (s *AliOSSService) Probe(ctx context.Context) error {
+	_, err := s.client.ListObjectsV2(ctx, &oss.ListObjectsV2Request{
+		Bucket:  oss.Ptr(s.config.BucketName),
+		MaxKeys: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("访问存储桶失败: %w", err)
+	}
+	return nil
+}
+
 // AliOSSFactory 阿里云OSS服务工厂
 type AliOSSFactory struct{}
 
@@ -5,19 +5,33 @@ import (
 	"os"
 
 	"github.com/joho/godotenv"
+
+	"go-uploader/storage"
 )
 
 // LoadAliOSSConfigFromEnv 从环境变量加载OSS配置
 func LoadAliOSSConfigFromEnv() (*AliOSSConfig, error) {
+	return LoadAliOSSConfigFromEnvPrefix("OSS")
+}
+
+// LoadAliOSSConfigFromEnvPrefix 从带指定前缀的环境变量加载OSS配置，例如前缀 OSS 对应 OSS_ACCESS_KEY_ID
+func LoadAliOSSConfigFromEnvPrefix(prefix string) (*AliOSSConfig, error) {
 	// 尝试加载.env文件，但不强制要求
 	_ = godotenv.Load()
 
 	config := &AliOSSConfig{
-		AccessKeyID:     os.Getenv("OSS_ACCESS_KEY_ID"),
-		AccessKeySecret: os.Getenv("OSS_ACCESS_KEY_SECRET"),
-		Region:          os.Getenv("OSS_REGION"),
-		BucketName:      os.Getenv("OSS_BUCKET_NAME"),
+		AccessKeyID:     os.Getenv(prefix + "_ACCESS_KEY_ID"),
+		AccessKeySecret: os.Getenv(prefix + "_ACCESS_KEY_SECRET"),
+		Region:          os.Getenv(prefix + "_REGION"),
+		BucketName:      os.Getenv(prefix + "_BUCKET_NAME"),
+	}
+
+	// 分片上传配置：<前缀>_PART_SIZE_MB、<前缀>_PARALLEL_NUM、<前缀>_ADAPTIVE_MULTIPART
+	multipart, err := storage.LoadMultipartConfigFromEnv(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("OSS配置验证失败: %w", err)
 	}
+	config.Multipart = multipart
 
 	// 验证配置
 	if err := config.Validate(); err != nil {
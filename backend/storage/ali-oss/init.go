@@ -17,4 +17,13 @@ func init() {
 
 	// 注册到全局工厂
 	storage.RegisterStorageFactory("ali-oss", factory)
+
+	// 注册配置加载函数，用于按名称配置多个存储后端
+	storage.RegisterConfigLoader("ali-oss", "OSS", func(prefix string) (storage.StorageConfig, error) {
+		config, err := LoadAliOSSConfigFromEnvPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return config, nil
+	})
 }
@@ -0,0 +1,35 @@
+package alioss
+
+import (
+	"context"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+
+	"go-uploader/storage"
+)
+
+// throttledClient 包装OSS客户端，按上下文中的带宽限速器限制上传请求体的读取速度
+// Uploader通过本地文件路径上传（保留断点续传），只能在分片请求这一层包装读取器
+type throttledClient struct {
+	*oss.Client
+}
+
+// PutObject 简单上传，小于分片大小的文件走这里
+func (c *throttledClient) PutObject(ctx context.Context, request *oss.PutObjectRequest, optFns ...func(*oss.Options)) (*oss.PutObjectResult, error) {
+	if limiter := storage.RateLimiterFrom(ctx); limiter != nil && request.Body != nil {
+		throttled := *request
+		throttled.Body = storage.NewThrottledReader(ctx, request.Body, limiter)
+		request = &throttled
+	}
+	return c.Client.PutObject(ctx, request, optFns...)
+}
+
+// UploadPart 上传分片
+func (c *throttledClient) UploadPart(ctx context.Context, request *oss.UploadPartRequest, optFns ...func(*oss.Options)) (*oss.UploadPartResult, error) {
+	if limiter := storage.RateLimiterFrom(ctx); limiter != nil && request.Body != nil {
+		throttled := *request
+		throttled.Body = storage.NewThrottledReader(ctx, request.Body, limiter)
+		request = &throttled
+	}
+	return c.Client.UploadPart(ctx, request, optFns...)
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteZipArchive 将对象逐个从存储服务读取并以zip格式写入w，不在本地落盘
+// trimPrefix 会从zip内的文件路径中去掉，method 为 zip.Store 或 zip.Deflate
+func WriteZipArchive(ctx context.Context, w io.Writer, svc StorageService, objects []ObjectInfo, trimPrefix string, method uint16) error {
+	zw := zip.NewWriter(w)
+
+	for _, obj := range objects {
+		// 客户端断开或请求被取消时停止读取后续对象
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(obj.Key, trimPrefix), "/")
+		if name == "" || strings.HasSuffix(name, "/") {
+			// 跳过目录占位对象
+			continue
+		}
+
+		if err := writeZipEntry(ctx, zw, svc, obj.Key, name, method); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeZipEntry 将单个对象写入zip
+func writeZipEntry(ctx context.Context, zw *zip.Writer, svc StorageService, key string, name string, method uint16) error {
+	reader, info, err := svc.GetObject(ctx, key)
+	if err != nil {
+		return fmt.Errorf("读取对象 %s 失败: %w", key, err)
+	}
+	defer reader.Close()
+
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   method,
+		Modified: info.LastModified,
+	}
+	entry, err := zw.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("创建压缩条目 %s 失败: %w", name, err)
+	}
+
+	if _, err := io.Copy(entry, reader); err != nil {
+		return fmt.Errorf("写入压缩条目 %s 失败: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,447 @@
+package azureblob
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+
+	"go-uploader/storage"
+)
+
+// sasClockSkew SAS的生效时间提前的时长，避免客户端与Azure的时钟误差导致链接暂时无效
+const sasClockSkew = 5 * time.Minute
+
+// AzureBlobService Azure Blob存储服务实现
+type AzureBlobService struct {
+	client     *container.Client
+	credential *azblob.SharedKeyCredential
+	config     *AzureBlobConfig
+	tuner      *storage.ParallelismTuner
+	protocol   sas.Protocol
+}
+
+// NewAzureBlobService 创建新的Azure Blob存储服务，容器不存在时自动创建
+func NewAzureBlobService(config *AzureBlobConfig) (*AzureBlobService, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	accountName, accountKey, serviceURL, err := config.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	credential, err := azblob.NewSharedKeyCredential(accountName, accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("Azure Blob账户密钥无效: %w", err)
+	}
+
+	// 初始化容器客户端
+	client, err := container.NewClientWithSharedKeyCredential(serviceURL+"/"+url.PathEscape(config.ContainerName), credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("初始化Azure Blob客户端失败: %w", err)
+	}
+
+	// 检查并确保容器存在
+	if _, err := client.GetProperties(context.Background(), nil); err != nil {
+		if !bloberror.HasCode(err, bloberror.ContainerNotFound) {
+			return nil, fmt.Errorf("检查容器失败: %w", err)
+		}
+		if _, err := client.Create(context.Background(), nil); err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+			return nil, fmt.Errorf("创建容器失败: %w", err)
+		}
+	}
+
+	// 本地模拟器（Azurite）通常使用http，SAS需要允许http访问
+	protocol := sas.ProtocolHTTPS
+	if strings.HasPrefix(serviceURL, "http://") {
+		protocol = sas.ProtocolHTTPSandHTTP
+	}
+
+	return &AzureBlobService{
+		client:     client,
+		credential: credential,
+		config:     config,
+		tuner:      storage.NewParallelismTuner(config.Multipart),
+		protocol:   protocol,
+	}, nil
+}
+
+// contentTypeOf 按文件扩展名返回MIME类型
+func contentTypeOf(name string) string {
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// etagString 去掉ETag两端的引号
+func etagString(etag *azcore.ETag) string {
+	if etag == nil {
+		return ""
+	}
+	return strings.Trim(string(*etag), "\"")
+}
+
+// deref 返回指针指向的值，指针为nil时返回零值
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// isNotFound 返回错误是否表示Blob不存在
+func isNotFound(err error) bool {
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return true
+	}
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// blockIDPrefix 返回块ID的前缀，由文件大小、修改时间和块大小决定
+// 同一个本地文件再次上传时生成相同的块ID，可以直接复用Azure上已暂存但未提交的块
+func blockIDPrefix(fileInfo os.FileInfo, partSize int64) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d/%d/%d", fileInfo.Size(), fileInfo.ModTime().UnixNano(), partSize)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// blockID 返回指定序号的块ID，同一个Blob的所有块ID编码前的长度必须相同
+func blockID(prefix string, partNumber int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%06d", prefix, partNumber)))
+}
+
+// UploadFile 上传文件到Azure Blob
+// 小文件一次上传，大文件分块暂存后提交块列表
+func (s *AzureBlobService) UploadFile(ctx context.Context, objectName string, localFile string, progressFn storage.ProgressCallback) (interface{}, error) {
+	// 检查文件是否存在和可访问
+	fileInfo, err := os.Stat(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("文件访问错误: %w", err)
+	}
+
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+	contentType := contentTypeOf(localFile)
+
+	file, err := os.Open(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开文件: %w", err)
+	}
+	defer file.Close()
+
+	// 按文件大小选择块大小，并行数不超过上传调度器分配的配额
+	partSize := s.config.Multipart.PartSizeFor(fileInfo.Size())
+	parallelism := s.tuner.Parallelism(storage.PartParallelism(ctx))
+	blockClient := s.client.NewBlockBlobClient(objectName)
+	headers := &blob.HTTPHeaders{BlobContentType: &contentType}
+
+	startTime := time.Now()
+	var etag *azcore.ETag
+	var lastModified *time.Time
+	if fileInfo.Size() > partSize {
+		resp, err := s.uploadBlocks(ctx, blockClient, file, fileInfo, headers, partSize, parallelism, progressFn)
+		if err != nil {
+			return nil, err
+		}
+		etag, lastModified = resp.ETag, resp.LastModified
+	} else {
+		// 小文件直接上传，上下文中指定了带宽限速器时按限速读取文件
+		progress := storage.NewPartProgress(fileInfo.Size(), progressFn)
+		reader := progress.Reader(storage.NewThrottledReader(ctx, file, storage.RateLimiterFrom(ctx)).(io.ReadSeeker))
+		resp, err := blockClient.Upload(ctx, streaming.NopCloser(reader), &blockblob.UploadOptions{HTTPHeaders: headers})
+		if err != nil {
+			return nil, fmt.Errorf("上传文件失败: %w", err)
+		}
+		etag, lastModified = resp.ETag, resp.LastModified
+	}
+	s.tuner.Observe(fileInfo.Size(), partSize, parallelism, time.Since(startTime))
+
+	return &storage.ObjectInfo{
+		Key:          objectName,
+		Size:         fileInfo.Size(),
+		ContentType:  contentType,
+		ETag:         etagString(etag),
+		LastModified: deref(lastModified),
+	}, nil
+}
+
+// uploadBlocks 并行暂存各个块，全部完成后按顺序提交块列表
+// Azure会保留未提交的块（7天后自动清理），上传失败、暂停或服务重启后再次上传同一个本地文件时跳过已暂存的块
+func (s *AzureBlobService) uploadBlocks(ctx context.Context, blockClient *blockblob.Client, file *os.File, fileInfo os.FileInfo,
+	headers *blob.HTTPHeaders, partSize int64, parallelism int, progressFn storage.ProgressCallback) (blockblob.CommitBlockListResponse, error) {
+	fileSize := fileInfo.Size()
+	totalParts := int((fileSize + partSize - 1) / partSize)
+	prefix := blockIDPrefix(fileInfo, partSize)
+
+	blockIDs := make([]string, totalParts)
+	for i := range blockIDs {
+		blockIDs[i] = blockID(prefix, i+1)
+	}
+
+	// 以服务端已暂存的块为准，块ID包含文件信息，文件变化后旧的块不会被误用
+	staged := make(map[string]int64)
+	list, err := blockClient.GetBlockList(ctx, blockblob.BlockListTypeUncommitted, nil)
+	if err != nil && !isNotFound(err) {
+		return blockblob.CommitBlockListResponse{}, fmt.Errorf("查询已暂存的块失败: %w", err)
+	}
+	if err == nil {
+		for _, block := range list.UncommittedBlocks {
+			staged[deref(block.Name)] = deref(block.Size)
+		}
+	}
+
+	// 已暂存的块直接计入进度
+	done := make([]bool, totalParts)
+	var doneBytes int64
+	for i, id := range blockIDs {
+		size := min(partSize, fileSize-int64(i)*partSize)
+		if stagedSize, ok := staged[id]; ok && stagedSize == size {
+			done[i] = true
+			doneBytes += size
+		}
+	}
+	progress := storage.NewPartProgress(fileSize, progressFn)
+	progress.Skip(doneBytes)
+
+	// 并行暂存剩余的块，任意块失败时取消其余块
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mutex    sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	limiter := storage.RateLimiterFrom(ctx)
+	parts := make(chan int)
+	for i := 0; i < max(parallelism, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range parts {
+				offset := int64(part) * partSize
+				section := io.NewSectionReader(file, offset, min(partSize, fileSize-offset))
+				reader := progress.Reader(storage.NewThrottledReader(uploadCtx, section, limiter).(io.ReadSeeker))
+
+				_, err := blockClient.StageBlock(uploadCtx, blockIDs[part], streaming.NopCloser(reader), nil)
+				if err != nil {
+					mutex.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("上传块%d失败: %w", part+1, err)
+						cancel()
+					}
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for part := 0; part < totalParts; part++ {
+		if done[part] {
+			continue
+		}
+		select {
+		case parts <- part:
+		case <-uploadCtx.Done():
+			break feed
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if firstErr != nil {
+		return blockblob.CommitBlockListResponse{}, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return blockblob.CommitBlockListResponse{}, err
+	}
+
+	resp, err := blockClient.CommitBlockList(ctx, blockIDs, &blockblob.CommitBlockListOptions{HTTPHeaders: headers})
+	if err != nil {
+		return blockblob.CommitBlockListResponse{}, fmt.Errorf("提交块列表失败: %w", err)
+	}
+	return resp, nil
+}
+
+// IsObjectExist 检查对象是否存在于Azure Blob
+func (s *AzureBlobService) IsObjectExist(ctx context.Context, objectName string) (bool, error) {
+	_, err := s.StatObject(ctx, objectName)
+	if errors.Is(err, storage.ErrObjectNotExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("检查对象是否存在失败: %w", err)
+	}
+	return true, nil
+}
+
+// StatObject 获取Blob的元数据
+func (s *AzureBlobService) StatObject(ctx context.Context, objectName string) (*storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	props, err := s.client.NewBlobClient(objectName).GetProperties(ctx, nil)
+	if isNotFound(err) {
+		return nil, storage.ErrObjectNotExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取对象元数据失败: %w", err)
+	}
+
+	return &storage.ObjectInfo{
+		Key:          objectName,
+		Size:         deref(props.ContentLength),
+		ContentType:  deref(props.ContentType),
+		ETag:         etagString(props.ETag),
+		LastModified: deref(props.LastModified),
+	}, nil
+}
+
+// ListObjects 列出指定前缀下的所有Blob
+func (s *AzureBlobService) ListObjects(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	// 确保前缀没有前导斜杠
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	var objects []storage.ObjectInfo
+	pager := s.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("列出对象失败: %w", err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			info := storage.ObjectInfo{Key: deref(item.Name)}
+			if props := item.Properties; props != nil {
+				info.Size = deref(props.ContentLength)
+				info.ContentType = deref(props.ContentType)
+				info.ETag = etagString(props.ETag)
+				info.LastModified = deref(props.LastModified)
+			}
+			objects = append(objects, info)
+		}
+	}
+
+	return objects, nil
+}
+
+// GetObject 读取Blob内容
+func (s *AzureBlobService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	resp, err := s.client.NewBlobClient(objectName).DownloadStream(ctx, nil)
+	if isNotFound(err) {
+		return nil, nil, storage.ErrObjectNotExists
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取对象失败: %w", err)
+	}
+
+	return resp.Body, &storage.ObjectInfo{
+		Key:          objectName,
+		Size:         deref(resp.ContentLength),
+		ContentType:  deref(resp.ContentType),
+		ETag:         etagString(resp.ETag),
+		LastModified: deref(resp.LastModified),
+	}, nil
+}
+
+// signBlobURL 使用账户密钥为Blob生成带SAS的URL
+func (s *AzureBlobService) signBlobURL(objectName string, permissions sas.BlobPermissions, expiration time.Duration, contentDisposition string) (string, error) {
+	now := time.Now().UTC()
+	params, err := sas.BlobSignatureValues{
+		Protocol:           s.protocol,
+		StartTime:          now.Add(-sasClockSkew),
+		ExpiryTime:         now.Add(expiration),
+		Permissions:        permissions.String(),
+		ContainerName:      s.config.ContainerName,
+		BlobName:           objectName,
+		ContentDisposition: contentDisposition,
+	}.SignWithSharedKey(s.credential)
+	if err != nil {
+		return "", err
+	}
+	return s.client.NewBlobClient(objectName).URL() + "?" + params.Encode(), nil
+}
+
+// GeneratePresignedURL 生成带SAS的上传URL，上传时需要带上返回的 x-ms-blob-type 请求头
+func (s *AzureBlobService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	presignedURL, err := s.signBlobURL(objectName, sas.BlobPermissions{Create: true, Write: true}, expiration, "")
+	if err != nil {
+		return "", nil, fmt.Errorf("生成预签名上传URL失败: %w", err)
+	}
+
+	// 通过SAS上传单个文件时必须指定Blob类型
+	headers := map[string]string{
+		"x-ms-blob-type": "BlockBlob",
+	}
+
+	return presignedURL, headers, nil
+}
+
+// GeneratePresignedDownloadURL 生成带SAS的下载URL，下载时使用原文件名
+func (s *AzureBlobService) GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	contentDisposition := mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(objectName)})
+	presignedURL, err := s.signBlobURL(objectName, sas.BlobPermissions{Read: true}, expiration, contentDisposition)
+	if err != nil {
+		return "", nil, fmt.Errorf("生成预签名下载URL失败: %w", err)
+	}
+
+	return presignedURL, make(map[string]string), nil
+}
+
+// GetBucketDomain 返回容器的地址
+func (s *AzureBlobService) GetBucketDomain() string {
+	return s.client.URL()
+}
+
+// Probe 查询容器属性，检查网络、账户密钥和容器是否可用
+func (s *AzureBlobService) Probe(ctx context.Context) error {
+	if _, err := s.client.GetProperties(ctx, nil); err != nil {
+		return fmt.Errorf("访问容器失败: %w", err)
+	}
+	return nil
+}
+
+// IsRetryableError 识别Azure返回的错误，服务繁忙、超时和服务端错误可以重试
+func (s *AzureBlobService) IsRetryableError(err error) (retryable, known bool) {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode == 0 {
+		return false, false
+	}
+	switch bloberror.Code(respErr.ErrorCode) {
+	case bloberror.ServerBusy, bloberror.OperationTimedOut, bloberror.InternalError:
+		return true, true
+	}
+	return storage.RetryableStatus(respErr.StatusCode), true
+}
@@ -0,0 +1,149 @@
+package azureblob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"go-uploader/storage"
+)
+
+// newTestService 使用 AZURE_BLOB_TEST_ 前缀的环境变量连接Azure Blob或Azurite，未配置时跳过测试
+// 测试上传的对象放在 go-uploader-test/ 下，不会自动删除，请使用专门的测试容器
+//
+//	AZURE_BLOB_TEST_CONNECTION_STRING="DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=...;BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;" \
+//	AZURE_BLOB_TEST_CONTAINER_NAME=uploads go test ./storage/azure-blob/
+func newTestService(t *testing.T) (*AzureBlobService, *AzureBlobConfig) {
+	t.Helper()
+
+	if os.Getenv("AZURE_BLOB_TEST_CONNECTION_STRING") == "" && os.Getenv("AZURE_BLOB_TEST_ACCOUNT_NAME") == "" {
+		t.Skip("未设置 AZURE_BLOB_TEST_CONNECTION_STRING 或 AZURE_BLOB_TEST_ACCOUNT_NAME，跳过Azure Blob存储测试")
+	}
+	config, err := LoadAzureBlobConfigFromEnvPrefix("AZURE_BLOB_TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewAzureBlobService(config)
+	if err != nil {
+		t.Fatalf("创建Azure Blob存储服务失败: %v", err)
+	}
+	return s, config
+}
+
+// writeTempFile 在临时目录中创建内容为data的文件
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	localFile := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(localFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return localFile
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, config := newTestService(t)
+	ctx := context.Background()
+	prefix := fmt.Sprintf("go-uploader-test/%d/", time.Now().UnixNano())
+
+	// 超过分块大小的文件按块上传
+	large := make([]byte, config.Multipart.PartSizeFor(0)+1024)
+	rand.Read(large)
+	files := map[string][]byte{
+		prefix + "docs/report 2024.txt": []byte("quarterly report"),
+		prefix + "docs/large.bin":       large,
+		prefix + "docs2/other.txt":      []byte("not under docs/"),
+	}
+	for objectName, data := range files {
+		var transferred int64
+		progressFn := func(increment, current, total int64) { transferred = current }
+		if _, err := s.UploadFile(ctx, objectName, writeTempFile(t, filepath.Base(objectName), data), progressFn); err != nil {
+			t.Fatalf("上传 %s 失败: %v", objectName, err)
+		}
+		if transferred != int64(len(data)) {
+			t.Errorf("上传 %s 的进度为 %d，期望 %d", objectName, transferred, len(data))
+		}
+	}
+
+	info, err := s.StatObject(ctx, prefix+"docs/large.bin")
+	if err != nil {
+		t.Fatalf("获取元数据失败: %v", err)
+	}
+	if info.Size != int64(len(large)) {
+		t.Errorf("元数据中的大小为 %d，期望 %d", info.Size, len(large))
+	}
+	if _, err := s.StatObject(ctx, prefix+"docs/missing.txt"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("不存在的对象返回 %v，期望 ErrObjectNotExists", err)
+	}
+
+	objects, err := s.ListObjects(ctx, prefix+"docs/")
+	if err != nil {
+		t.Fatalf("列出对象失败: %v", err)
+	}
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != prefix+"docs/large.bin" || keys[1] != prefix+"docs/report 2024.txt" {
+		t.Errorf("列出的对象为 %v", keys)
+	}
+
+	reader, _, err := s.GetObject(ctx, prefix+"docs/large.bin")
+	if err != nil {
+		t.Fatalf("读取对象失败: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(data, large) {
+		t.Errorf("读取的内容与上传的不一致: %d 字节, %v", len(data), err)
+	}
+}
+
+func TestPresign(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	objectName := fmt.Sprintf("go-uploader-test/%d/报告 \"final\".txt", time.Now().UnixNano())
+
+	uploadURL, headers, err := s.GeneratePresignedURL(ctx, objectName, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("生成预签名上传URL失败: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader([]byte("presigned upload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("预签名上传失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("预签名上传返回 %s", resp.Status)
+	}
+
+	downloadURL, _, err := s.GeneratePresignedDownloadURL(ctx, objectName, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("生成预签名下载URL失败: %v", err)
+	}
+	resp, err = http.Get(downloadURL)
+	if err != nil {
+		t.Fatalf("预签名下载失败: %v", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != "presigned upload" {
+		t.Errorf("预签名下载返回 %s: %q, %v", resp.Status, data, err)
+	}
+}
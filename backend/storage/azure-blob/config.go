@@ -0,0 +1,127 @@
+package azureblob
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"go-uploader/storage"
+)
+
+// AzureBlobConfig Azure Blob存储配置
+// 使用账户名和账户密钥（共享密钥），或者包含 AccountKey 的连接字符串，二选一
+type AzureBlobConfig struct {
+	ConnectionString string // 连接字符串，配置后忽略账户名、账户密钥和服务地址
+	AccountName      string
+	AccountKey       string
+	Endpoint         string // Blob服务地址，默认 https://<账户名>.blob.core.windows.net，Azurite为 http://127.0.0.1:10000/devstoreaccount1
+	ContainerName    string
+	Multipart        storage.MultipartConfig
+}
+
+// GetType 返回存储类型标识
+func (c *AzureBlobConfig) GetType() string {
+	return storage.TypeAzureBlob
+}
+
+// Validate 验证配置的合法性
+func (c *AzureBlobConfig) Validate() error {
+	if c.ContainerName == "" {
+		return fmt.Errorf("缺少必要的Azure Blob配置")
+	}
+	if c.ConnectionString != "" {
+		if _, _, _, err := parseConnectionString(c.ConnectionString); err != nil {
+			return err
+		}
+	} else if c.AccountName == "" || c.AccountKey == "" {
+		return fmt.Errorf("缺少必要的Azure Blob配置，需要账户名和账户密钥或连接字符串")
+	}
+	if c.Endpoint != "" {
+		u, err := url.Parse(c.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("Azure Blob服务地址必须是完整的http或https地址: %s", c.Endpoint)
+		}
+	}
+	return c.Multipart.Validate()
+}
+
+// Credentials 返回账户名、账户密钥和Blob服务地址，配置了连接字符串时从连接字符串解析
+func (c *AzureBlobConfig) Credentials() (accountName, accountKey, serviceURL string, err error) {
+	if c.ConnectionString != "" {
+		return parseConnectionString(c.ConnectionString)
+	}
+	serviceURL = c.Endpoint
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net", c.AccountName)
+	}
+	return c.AccountName, c.AccountKey, strings.TrimSuffix(serviceURL, "/"), nil
+}
+
+// parseConnectionString 解析Azure存储连接字符串，例如
+// DefaultEndpointsProtocol=https;AccountName=xxx;AccountKey=xxx;EndpointSuffix=core.windows.net
+// 生成SAS需要账户密钥，因此只支持包含 AccountKey 的连接字符串
+func parseConnectionString(connectionString string) (accountName, accountKey, serviceURL string, err error) {
+	values := make(map[string]string)
+	for _, field := range strings.Split(connectionString, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		values[strings.ToLower(key)] = value
+	}
+
+	accountName, accountKey = values["accountname"], values["accountkey"]
+	if accountName == "" || accountKey == "" {
+		return "", "", "", fmt.Errorf("Azure Blob连接字符串必须包含AccountName和AccountKey")
+	}
+
+	serviceURL = values["blobendpoint"]
+	if serviceURL == "" {
+		protocol := values["defaultendpointsprotocol"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		suffix := values["endpointsuffix"]
+		if suffix == "" {
+			suffix = "core.windows.net"
+		}
+		serviceURL = fmt.Sprintf("%s://%s.blob.%s", protocol, accountName, suffix)
+	}
+	return accountName, accountKey, strings.TrimSuffix(serviceURL, "/"), nil
+}
+
+// LoadAzureBlobConfigFromEnv 从环境变量加载Azure Blob配置
+func LoadAzureBlobConfigFromEnv() (*AzureBlobConfig, error) {
+	return LoadAzureBlobConfigFromEnvPrefix("AZURE_BLOB")
+}
+
+// LoadAzureBlobConfigFromEnvPrefix 从带指定前缀的环境变量加载Azure Blob配置，例如前缀 AZURE_BLOB 对应 AZURE_BLOB_ACCOUNT_NAME
+func LoadAzureBlobConfigFromEnvPrefix(prefix string) (*AzureBlobConfig, error) {
+	// 尝试加载.env文件，但不强制要求
+	_ = godotenv.Load()
+
+	config := &AzureBlobConfig{
+		ConnectionString: os.Getenv(prefix + "_CONNECTION_STRING"),
+		AccountName:      os.Getenv(prefix + "_ACCOUNT_NAME"),
+		AccountKey:       os.Getenv(prefix + "_ACCOUNT_KEY"),
+		Endpoint:         os.Getenv(prefix + "_ENDPOINT"),
+		ContainerName:    os.Getenv(prefix + "_CONTAINER_NAME"),
+	}
+
+	// 分块上传配置：<前缀>_PART_SIZE_MB、<前缀>_PARALLEL_NUM、<前缀>_ADAPTIVE_MULTIPART
+	multipart, err := storage.LoadMultipartConfigFromEnv(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("Azure Blob配置验证失败: %w", err)
+	}
+	config.Multipart = multipart
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("Azure Blob配置验证失败: %w", err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,29 @@
+package azureblob
+
+import (
+	"go-uploader/storage"
+)
+
+// 在init函数中注册Azure Blob存储服务工厂
+func init() {
+	// 创建工厂函数
+	factory := func(config storage.StorageConfig) (storage.StorageService, error) {
+		azureConfig, ok := config.(*AzureBlobConfig)
+		if !ok {
+			return nil, storage.ErrInvalidConfig
+		}
+		return NewAzureBlobService(azureConfig)
+	}
+
+	// 注册到全局工厂
+	storage.RegisterStorageFactory(storage.TypeAzureBlob, factory)
+
+	// 注册配置加载函数，用于按名称配置多个存储后端
+	storage.RegisterConfigLoader(storage.TypeAzureBlob, "AZURE_BLOB", func(prefix string) (storage.StorageConfig, error) {
+		config, err := LoadAzureBlobConfigFromEnvPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return config, nil
+	})
+}
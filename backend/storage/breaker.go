@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"    // 正常放行请求
+	CircuitOpen     = "open"      // 熔断中，直接拒绝请求
+	CircuitHalfOpen = "half-open" // 熔断时间结束，放行一个试探请求
+)
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	FailureThreshold int           // 连续失败多少次后熔断
+	OpenTimeout      time.Duration // 熔断持续时间，结束后放行试探请求
+}
+
+// LoadBreakerConfigFromEnv 从环境变量加载熔断器配置
+// 调用方负责加载.env文件
+func LoadBreakerConfigFromEnv() (BreakerConfig, error) {
+	config := BreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+
+	if value := os.Getenv("STORAGE_BREAKER_FAILURES"); value != "" {
+		failures, err := strconv.Atoi(value)
+		if err != nil || failures <= 0 {
+			return config, fmt.Errorf("STORAGE_BREAKER_FAILURES 必须是正整数: %s", value)
+		}
+		config.FailureThreshold = failures
+	}
+	if value := os.Getenv("STORAGE_BREAKER_OPEN_SECONDS"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return config, fmt.Errorf("STORAGE_BREAKER_OPEN_SECONDS 必须是正整数: %s", value)
+		}
+		config.OpenTimeout = time.Duration(seconds) * time.Second
+	}
+
+	return config, nil
+}
+
+// BreakerStats 熔断器状态
+type BreakerStats struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Trips               int64      `json:"trips"`               // 累计熔断次数
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`  // 最近一次熔断的时间
+	LastError           string     `json:"lastError,omitempty"` // 最近一次失败的错误
+}
+
+// CircuitBreaker 为存储服务添加熔断的装饰器
+// 连续失败达到阈值后熔断，熔断期间直接返回 ErrCircuitOpen；熔断时间结束后放行一个试探请求，
+// 成功则恢复，失败则重新熔断。Probe 不受熔断限制，就绪检查成功时同样会恢复
+type CircuitBreaker struct {
+	inner         StorageService
+	config        BreakerConfig
+	onStateChange func(from, to string, err error)
+
+	mutex       sync.Mutex
+	state       string
+	failures    int
+	trips       int64
+	openedAt    time.Time
+	lastErr     error
+	trialActive bool // 半开状态下是否已有试探请求
+}
+
+// NewCircuitBreaker 创建带熔断的存储服务，onStateChange 在状态变化时调用，可以为nil
+func NewCircuitBreaker(inner StorageService, config BreakerConfig, onStateChange func(from, to string, err error)) *CircuitBreaker {
+	return &CircuitBreaker{
+		inner:         inner,
+		config:        config,
+		onStateChange: onStateChange,
+		state:         CircuitClosed,
+	}
+}
+
+// Unwrap 返回被装饰的存储服务
+func (b *CircuitBreaker) Unwrap() StorageService {
+	return b.inner
+}
+
+// Stats 返回熔断器状态
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats := BreakerStats{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Trips:               b.trips,
+	}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	if b.lastErr != nil {
+		stats.LastError = b.lastErr.Error()
+	}
+	return stats
+}
+
+// Healthy 返回熔断器是否处于关闭状态
+func (b *CircuitBreaker) Healthy() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state == CircuitClosed
+}
+
+// allow 返回是否放行请求，trial 表示放行的是半开状态下的试探请求
+func (b *CircuitBreaker) allow() (trial bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.config.OpenTimeout {
+			return false, ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen, nil)
+		b.trialActive = true
+		return true, nil
+	case CircuitHalfOpen:
+		if b.trialActive {
+			return false, ErrCircuitOpen
+		}
+		b.trialActive = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record 记录请求结果
+func (b *CircuitBreaker) record(ctx context.Context, trial bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if trial {
+		b.trialActive = false
+	}
+
+	// 主动取消或暂停的请求不能反映存储服务的状态，超时仍然计为失败
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+
+	if !isBackendFailure(err) {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			b.setState(CircuitClosed, nil)
+		}
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.config.FailureThreshold) {
+		b.openedAt = time.Now()
+		b.trips++
+		b.setState(CircuitOpen, err)
+	}
+}
+
+// setState 切换状态并通知，调用方需要持有锁
+func (b *CircuitBreaker) setState(state string, err error) {
+	from := b.state
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(from, state, err)
+	}
+}
+
+// isBackendFailure 返回错误是否说明存储服务故障，对象不存在和本地文件错误不计入
+func isBackendFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrObjectNotExists) || errors.Is(err, ErrObjectExists) {
+		return false
+	}
+	var pathErr *fs.PathError
+	return !errors.As(err, &pathErr)
+}
+
+// call 经过熔断器执行操作
+func (b *CircuitBreaker) call(ctx context.Context, fn func() error) error {
+	trial, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	b.record(ctx, trial, err)
+	return err
+}
+
+// UploadFile 上传文件
+func (b *CircuitBreaker) UploadFile(ctx context.Context, objectName string, localFile string, progressFn ProgressCallback) (interface{}, error) {
+	var result interface{}
+	err := b.call(ctx, func() error {
+		var err error
+		result, err = b.inner.UploadFile(ctx, objectName, localFile, progressFn)
+		return err
+	})
+	return result, err
+}
+
+// IsObjectExist 检查对象是否存在
+func (b *CircuitBreaker) IsObjectExist(ctx context.Context, objectName string) (bool, error) {
+	var exists bool
+	err := b.call(ctx, func() error {
+		var err error
+		exists, err = b.inner.IsObjectExist(ctx, objectName)
+		return err
+	})
+	return exists, err
+}
+
+// StatObject 获取对象元数据
+func (b *CircuitBreaker) StatObject(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	var info *ObjectInfo
+	err := b.call(ctx, func() error {
+		var err error
+		info, err = b.inner.StatObject(ctx, objectName)
+		return err
+	})
+	return info, err
+}
+
+// ListObjects 列出指定前缀下的所有对象
+func (b *CircuitBreaker) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := b.call(ctx, func() error {
+		var err error
+		objects, err = b.inner.ListObjects(ctx, prefix)
+		return err
+	})
+	return objects, err
+}
+
+// GetObject 读取对象内容
+func (b *CircuitBreaker) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error) {
+	var reader io.ReadCloser
+	var info *ObjectInfo
+	err := b.call(ctx, func() error {
+		var err error
+		reader, info, err = b.inner.GetObject(ctx, objectName)
+		return err
+	})
+	return reader, info, err
+}
+
+// GeneratePresignedURL 生成预签名上传URL，签名在本地计算，不经过熔断器
+func (b *CircuitBreaker) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return b.inner.GeneratePresignedURL(ctx, objectName, expiration)
+}
+
+// GeneratePresignedDownloadURL 生成预签名下载URL，签名在本地计算，不经过熔断器
+func (b *CircuitBreaker) GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return b.inner.GeneratePresignedDownloadURL(ctx, objectName, expiration)
+}
+
+// GetBucketDomain 获取存储桶的域名
+func (b *CircuitBreaker) GetBucketDomain() string {
+	return b.inner.GetBucketDomain()
+}
+
+// Probe 检查存储服务是否可用，熔断期间同样会访问存储服务，结果计入熔断器
+func (b *CircuitBreaker) Probe(ctx context.Context) error {
+	err := b.inner.Probe(ctx)
+	b.record(ctx, false, err)
+	return err
+}
+
+// AbortStaleUploads 转发给被装饰的存储服务，不支持断点续传的存储服务返回0
+func (b *CircuitBreaker) AbortStaleUploads(ctx context.Context, maxAge time.Duration) (int, error) {
+	if aborter, ok := b.inner.(StaleUploadAborter); ok {
+		return aborter.AbortStaleUploads(ctx, maxAge)
+	}
+	return 0, nil
+}
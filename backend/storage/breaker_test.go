@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+// countingService 记录调用次数的内存存储服务，用于判断熔断器是否放行了请求
+type countingService struct {
+	*memoryService
+	calls int
+}
+
+func (c *countingService) StatObject(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	c.calls++
+	return c.memoryService.StatObject(ctx, objectName)
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	inner := &countingService{memoryService: newMemoryService("flaky")}
+	inner.put("a.txt", "data")
+	var transitions []string
+	b := NewCircuitBreaker(inner, BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute}, func(from, to string, err error) {
+		transitions = append(transitions, from+"->"+to)
+	})
+	outage := errors.New("connection refused")
+
+	steps := []struct {
+		name       string
+		innerErr   error  // 存储服务返回的错误
+		object     string // 读取的对象，默认为 a.txt
+		canceled   bool   // 请求被主动取消
+		elapse     bool   // 熔断时间已经结束
+		wantErr    error  // 期望的错误，为nil表示成功
+		wantCalled bool   // 是否放行到存储服务
+		wantState  string
+	}{
+		{name: "第1次失败", innerErr: outage, wantErr: outage, wantCalled: true, wantState: CircuitClosed},
+		{name: "对象不存在不计入失败并清零", object: "missing.txt", wantErr: ErrObjectNotExists, wantCalled: true, wantState: CircuitClosed},
+		{name: "清零后第1次失败", innerErr: outage, wantErr: outage, wantCalled: true, wantState: CircuitClosed},
+		{name: "主动取消不计入失败", innerErr: outage, canceled: true, wantErr: outage, wantCalled: true, wantState: CircuitClosed},
+		{name: "本地文件错误不计入失败", innerErr: &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrNotExist}, wantErr: fs.ErrNotExist, wantCalled: true, wantState: CircuitClosed},
+		{name: "清零后再连续失败", innerErr: outage, wantErr: outage, wantCalled: true, wantState: CircuitClosed},
+		{name: "清零后再连续失败", innerErr: outage, wantErr: outage, wantCalled: true, wantState: CircuitClosed},
+		{name: "达到阈值后熔断", innerErr: outage, wantErr: outage, wantCalled: true, wantState: CircuitOpen},
+		{name: "熔断期间直接拒绝", wantErr: ErrCircuitOpen, wantCalled: false, wantState: CircuitOpen},
+		{name: "试探请求失败后重新熔断", innerErr: outage, elapse: true, wantErr: outage, wantCalled: true, wantState: CircuitOpen},
+		{name: "重新熔断后直接拒绝", wantErr: ErrCircuitOpen, wantCalled: false, wantState: CircuitOpen},
+		{name: "试探请求成功后恢复", elapse: true, wantCalled: true, wantState: CircuitClosed},
+		{name: "恢复后正常放行", wantCalled: true, wantState: CircuitClosed},
+	}
+	for _, step := range steps {
+		inner.err = step.innerErr
+		if step.elapse {
+			b.openedAt = time.Now().Add(-time.Minute)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		if step.canceled {
+			cancel()
+		}
+		object := step.object
+		if object == "" {
+			object = "a.txt"
+		}
+
+		calls := inner.calls
+		_, err := b.StatObject(ctx, object)
+		cancel()
+
+		if step.wantErr == nil && err != nil || step.wantErr != nil && !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: 返回 %v，期望 %v", step.name, err, step.wantErr)
+		}
+		if called := inner.calls > calls; called != step.wantCalled {
+			t.Fatalf("%s: 放行到存储服务: %v，期望 %v", step.name, called, step.wantCalled)
+		}
+		if state := b.Stats().State; state != step.wantState {
+			t.Fatalf("%s: 状态为 %s，期望 %s", step.name, state, step.wantState)
+		}
+	}
+
+	stats := b.Stats()
+	if stats.Trips != 2 || stats.ConsecutiveFailures != 0 || stats.LastError != outage.Error() || stats.OpenedAt == nil {
+		t.Errorf("熔断统计不正确: %+v", stats)
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if fmt.Sprint(transitions) != fmt.Sprint(want) {
+		t.Errorf("状态变化为 %v，期望 %v", transitions, want)
+	}
+}
+
+func TestCircuitBreakerHalfOpenAllowsOneTrial(t *testing.T) {
+	b := NewCircuitBreaker(newMemoryService("flaky"), BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}, nil)
+	b.record(context.Background(), false, errors.New("connection refused"))
+	if b.Healthy() {
+		t.Fatal("失败次数达到阈值后仍然可用")
+	}
+
+	b.openedAt = time.Now().Add(-time.Minute)
+	if trial, err := b.allow(); err != nil || !trial {
+		t.Fatalf("熔断时间结束后没有放行试探请求: %v, %v", trial, err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("试探请求结束前放行了其他请求: %v", err)
+	}
+
+	// 试探请求被取消时不改变状态，之后可以重新试探
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(ctx, true, context.Canceled)
+	if state := b.Stats().State; state != CircuitHalfOpen {
+		t.Errorf("试探请求被取消后状态为 %s，期望 %s", state, CircuitHalfOpen)
+	}
+	if trial, err := b.allow(); err != nil || !trial {
+		t.Errorf("试探请求被取消后没有放行新的试探请求: %v, %v", trial, err)
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	inner := newMemoryService("flaky")
+	b := NewCircuitBreaker(inner, BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour}, nil)
+
+	// 就绪检查的失败计入熔断器，熔断期间就绪检查仍然访问存储服务，成功时恢复
+	inner.err = errors.New("connection refused")
+	for i := 0; i < 2; i++ {
+		if err := b.Probe(context.Background()); err == nil {
+			t.Fatal("存储服务故障时就绪检查成功")
+		}
+	}
+	if b.Healthy() {
+		t.Fatal("就绪检查连续失败后没有熔断")
+	}
+	inner.err = nil
+	if err := b.Probe(context.Background()); err != nil {
+		t.Fatalf("熔断期间就绪检查返回 %v", err)
+	}
+	if !b.Healthy() {
+		t.Error("就绪检查成功后没有恢复")
+	}
+}
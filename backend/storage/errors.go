@@ -17,4 +17,16 @@ var (
 
 	// ErrObjectNotExists 对象不存在错误
 	ErrObjectNotExists = errors.New("对象不存在")
+
+	// ErrCircuitOpen 存储服务连续失败后熔断，暂时拒绝请求
+	ErrCircuitOpen = errors.New("存储服务暂时不可用")
+
+	// ErrPresignNotSupported 存储服务不支持预签名URL（例如WebDAV）
+	ErrPresignNotSupported = errors.New("存储服务不支持预签名URL")
+
+	// ErrProxyLinkInvalid 代理下载链接的签名无效
+	ErrProxyLinkInvalid = errors.New("下载链接无效")
+
+	// ErrProxyLinkExpired 代理下载链接已过期
+	ErrProxyLinkExpired = errors.New("下载链接已过期")
 )
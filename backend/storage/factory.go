@@ -9,17 +9,32 @@ import (
 const (
 	TypeAliOSS = "ali-oss"
 	TypeMinIO  = "minio"
-	// 将来可以添加更多存储类型，如:
-	// TypeS3     = "s3"
+	TypeS3     = "s3" // 通用的S3兼容存储，例如AWS S3、Cloudflare R2、Ceph RGW、腾讯云COS
+	TypeWebDAV = "webdav"
+	TypeSFTP   = "sftp"
+	// TypeAzureBlob Azure Blob存储，也可以连接本地模拟器Azurite
+	TypeAzureBlob = "azure-blob"
+	// TypeReplicated 组合其他命名后端的复制后端，见 ReplicatedService
+	TypeReplicated = "replicated"
 )
 
 // StorageFactoryFunc 存储服务工厂函数类型
 type StorageFactoryFunc func(config StorageConfig) (StorageService, error)
 
-// 全局存储服务工厂注册表
+// StorageConfigLoader 从带指定前缀的环境变量加载存储配置，例如前缀 OSS 对应 OSS_ACCESS_KEY_ID
+type StorageConfigLoader func(prefix string) (StorageConfig, error)
+
+// configLoader 存储配置加载函数及其默认的环境变量前缀
+type configLoader struct {
+	defaultPrefix string
+	load          StorageConfigLoader
+}
+
+// 全局存储服务工厂和配置加载函数注册表
 var (
-	factoryMutex sync.RWMutex
-	factories    = make(map[string]StorageFactoryFunc)
+	factoryMutex  sync.RWMutex
+	factories     = make(map[string]StorageFactoryFunc)
+	configLoaders = make(map[string]configLoader)
 )
 
 // RegisterStorageFactory 注册存储服务工厂
@@ -41,3 +56,26 @@ func CreateStorageService(config StorageConfig) (StorageService, error) {
 
 	return factory(config)
 }
+
+// RegisterConfigLoader 注册存储配置加载函数，defaultPrefix 为只配置单个后端时使用的环境变量前缀
+func RegisterConfigLoader(storageType, defaultPrefix string, loader StorageConfigLoader) {
+	factoryMutex.Lock()
+	defer factoryMutex.Unlock()
+	configLoaders[storageType] = configLoader{defaultPrefix: defaultPrefix, load: loader}
+}
+
+// LoadStorageConfig 从带指定前缀的环境变量加载指定类型的存储配置，前缀为空时使用默认前缀
+func LoadStorageConfig(storageType, prefix string) (StorageConfig, error) {
+	factoryMutex.RLock()
+	loader, exists := configLoaders[storageType]
+	factoryMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("不支持的存储类型: %s", storageType)
+	}
+
+	if prefix == "" {
+		prefix = loader.defaultPrefix
+	}
+	return loader.load(prefix)
+}
@@ -0,0 +1,454 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 迁移时判断目标对象是否与源对象相同的方式
+const (
+	CompareSize = "size" // 大小相同
+	CompareETag = "etag" // 大小和ETag都相同
+	CompareHash = "hash" // 大小和内容的MD5都相同，ETag相同的非分片对象不再读取内容
+)
+
+// 迁移对象的结果
+const (
+	MigrationCopied  = "copied"  // 已复制到目标后端
+	MigrationSkipped = "skipped" // 目标后端已有相同的对象
+	MigrationFailed  = "failed"  // 复制失败
+	MigrationPlanned = "planned" // 试运行时需要复制的对象
+)
+
+// MigrationOptions 迁移选项
+type MigrationOptions struct {
+	Prefix  string            // 只迁移该前缀下的对象，为空表示全部
+	Workers int               // 并行复制的对象数
+	Compare string            // 判断对象相同的方式：size、etag 或 hash
+	DryRun  bool              // 只比较不复制，也不写入迁移记录
+	TempDir string            // 复制时暂存对象内容的本地目录
+	Journal *MigrationJournal // 迁移记录，已完成的对象在重新运行时跳过，可以为nil
+	Logf    func(format string, args ...interface{})
+}
+
+// MigrationEntry 一个对象的迁移结果
+type MigrationEntry struct {
+	Key   string    `json:"key"`
+	State string    `json:"state"`
+	Size  int64     `json:"size"`
+	ETag  string    `json:"etag,omitempty"` // 源对象的ETag
+	MD5   string    `json:"md5,omitempty"`  // 复制或比较时计算的内容MD5
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// MigrationMismatch 校验时发现的不一致
+type MigrationMismatch struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// MigrationReport 迁移报告
+type MigrationReport struct {
+	Prefix     string              `json:"prefix"`
+	Compare    string              `json:"compare"`
+	DryRun     bool                `json:"dryRun"`
+	StartedAt  time.Time           `json:"startedAt"`
+	FinishedAt time.Time           `json:"finishedAt"`
+	Total      int                 `json:"total"`      // 源后端的对象数
+	TotalBytes int64               `json:"totalBytes"` // 源后端的对象大小合计
+	Copied     int                 `json:"copied"`
+	Skipped    int                 `json:"skipped"` // 目标后端已有相同对象
+	Resumed    int                 `json:"resumed"` // 迁移记录中已完成的对象
+	Planned    int                 `json:"planned"` // 试运行时需要复制的对象
+	Failed     int                 `json:"failed"`
+	Bytes      int64               `json:"bytes"`    // 本次复制的字节数
+	Verified   int                 `json:"verified"` // 校验通过的对象数
+	Failures   []MigrationEntry    `json:"failures,omitempty"`
+	Mismatches []MigrationMismatch `json:"mismatches,omitempty"`
+}
+
+// OK 返回迁移是否没有失败和不一致
+func (r *MigrationReport) OK() bool {
+	return r.Failed == 0 && len(r.Mismatches) == 0
+}
+
+// MigrationJournal 迁移记录，每完成一个对象追加一行JSON，中断后重新运行时跳过已完成的对象
+type MigrationJournal struct {
+	mutex   sync.Mutex
+	file    *os.File
+	entries map[string]MigrationEntry
+}
+
+// OpenMigrationJournal 打开迁移记录，文件不存在时创建
+func OpenMigrationJournal(path string) (*MigrationJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建迁移记录目录失败: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开迁移记录失败: %w", err)
+	}
+
+	// 同一对象以最后一行为准，中断时写了一半的最后一行直接忽略
+	entries := make(map[string]MigrationEntry)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry MigrationEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+			continue
+		}
+		entries[entry.Key] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+
+	return &MigrationJournal{file: file, entries: entries}, nil
+}
+
+// Len 返回迁移记录中的对象数
+func (j *MigrationJournal) Len() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return len(j.entries)
+}
+
+// done 返回对象是否已经迁移完成，源对象在上次迁移后发生变化时视为未完成
+func (j *MigrationJournal) done(obj ObjectInfo) (MigrationEntry, bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	entry, ok := j.entries[obj.Key]
+	if !ok || (entry.State != MigrationCopied && entry.State != MigrationSkipped) {
+		return entry, false
+	}
+	return entry, entry.Size == obj.Size && entry.ETag == normalizeETag(obj.ETag)
+}
+
+// record 追加一个对象的迁移结果
+func (j *MigrationJournal) record(entry MigrationEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.entries[entry.Key] = entry
+	_, err = j.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close 关闭迁移记录
+func (j *MigrationJournal) Close() error {
+	return j.file.Close()
+}
+
+// normalizeETag 去掉ETag的引号并转为小写，不同存储服务返回的格式不完全相同
+func normalizeETag(etag string) string {
+	return strings.ToLower(strings.Trim(etag, "\""))
+}
+
+// isMultipartETag 返回ETag是否来自分片上传，分片上传的ETag不是内容的MD5
+func isMultipartETag(etag string) bool {
+	return strings.Contains(etag, "-")
+}
+
+// Migrate 将源存储服务中的对象复制到目标存储服务，最后校验目标后端的对象
+// 单个对象失败不会中止迁移，记录在报告中；上下文取消时停止并返回已完成部分的报告
+func Migrate(ctx context.Context, src, dst StorageService, opts MigrationOptions) (*MigrationReport, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Compare == "" {
+		opts.Compare = CompareETag
+	}
+	if opts.Compare != CompareSize && opts.Compare != CompareETag && opts.Compare != CompareHash {
+		return nil, fmt.Errorf("比较方式必须是 size、etag 或 hash: %s", opts.Compare)
+	}
+	if opts.Logf == nil {
+		opts.Logf = func(string, ...interface{}) {}
+	}
+	if !opts.DryRun {
+		if err := os.MkdirAll(opts.TempDir, 0755); err != nil {
+			return nil, fmt.Errorf("创建临时目录失败: %w", err)
+		}
+	}
+
+	report := &MigrationReport{
+		Prefix:    opts.Prefix,
+		Compare:   opts.Compare,
+		DryRun:    opts.DryRun,
+		StartedAt: time.Now(),
+	}
+
+	listed, err := src.ListObjects(ctx, opts.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("列出源对象失败: %w", err)
+	}
+	// 跳过目录占位对象
+	objects := make([]ObjectInfo, 0, len(listed))
+	for _, obj := range listed {
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		objects = append(objects, obj)
+		report.TotalBytes += obj.Size
+	}
+	report.Total = len(objects)
+	opts.Logf("源后端共有%d个对象, %d字节", report.Total, report.TotalBytes)
+
+	// 并行迁移，结果按源对象的顺序保存，校验时使用
+	entries := make([]MigrationEntry, len(objects))
+	var mutex sync.Mutex
+	var finished int
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				obj := objects[index]
+				entry, resumed := migrateObject(ctx, src, dst, obj, opts)
+
+				mutex.Lock()
+				entries[index] = entry
+				finished++
+				switch {
+				case resumed:
+					report.Resumed++
+				case entry.State == MigrationCopied:
+					report.Copied++
+					report.Bytes += entry.Size
+				case entry.State == MigrationSkipped:
+					report.Skipped++
+				case entry.State == MigrationPlanned:
+					report.Planned++
+				case entry.State == MigrationFailed:
+					report.Failed++
+					report.Failures = append(report.Failures, entry)
+				}
+				progress := fmt.Sprintf("[%d/%d]", finished, len(objects))
+				mutex.Unlock()
+
+				if resumed {
+					continue
+				}
+				if entry.Error != "" {
+					opts.Logf("%s %s %s: %s", progress, entry.State, entry.Key, entry.Error)
+				} else {
+					opts.Logf("%s %s %s (%d字节)", progress, entry.State, entry.Key, entry.Size)
+				}
+			}
+		}()
+	}
+
+	for index := range objects {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- index
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		report.FinishedAt = time.Now()
+		return report, err
+	}
+
+	if !opts.DryRun {
+		opts.Logf("校验目标后端的对象...")
+		if err := verifyMigration(ctx, dst, objects, entries, opts, report); err != nil {
+			report.FinishedAt = time.Now()
+			return report, err
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// migrateObject 迁移单个对象，resumed 表示迁移记录中已完成而没有再次检查
+func migrateObject(ctx context.Context, src, dst StorageService, obj ObjectInfo, opts MigrationOptions) (entry MigrationEntry, resumed bool) {
+	if opts.Journal != nil {
+		if entry, ok := opts.Journal.done(obj); ok {
+			return entry, true
+		}
+	}
+
+	entry = MigrationEntry{Key: obj.Key, Size: obj.Size, ETag: normalizeETag(obj.ETag)}
+	identical, md5sum, err := objectsIdentical(ctx, src, dst, obj, opts.Compare)
+	switch {
+	case err != nil:
+		entry.State = MigrationFailed
+		entry.Error = err.Error()
+	case identical:
+		entry.State = MigrationSkipped
+		entry.MD5 = md5sum
+	case opts.DryRun:
+		entry.State = MigrationPlanned
+	default:
+		entry.MD5, err = copyObject(ctx, src, dst, obj.Key, opts.TempDir)
+		entry.State = MigrationCopied
+		if err != nil {
+			entry.State = MigrationFailed
+			entry.Error = err.Error()
+		}
+	}
+	entry.Time = time.Now()
+
+	// 取消导致的失败不写入记录，下次运行时重新迁移
+	if opts.Journal != nil && !opts.DryRun && ctx.Err() == nil {
+		if err := opts.Journal.record(entry); err != nil {
+			opts.Logf("写入迁移记录失败: %s, %v", obj.Key, err)
+		}
+	}
+	return entry, false
+}
+
+// objectsIdentical 返回目标后端是否已有与源对象相同的对象，按内容比较时同时返回内容的MD5
+func objectsIdentical(ctx context.Context, src, dst StorageService, obj ObjectInfo, compare string) (bool, string, error) {
+	info, err := dst.StatObject(ctx, obj.Key)
+	if errors.Is(err, ErrObjectNotExists) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("获取目标对象信息失败: %w", err)
+	}
+	if info.Size != obj.Size {
+		return false, "", nil
+	}
+
+	srcETag, dstETag := normalizeETag(obj.ETag), normalizeETag(info.ETag)
+	switch compare {
+	case CompareSize:
+		return true, "", nil
+	case CompareETag:
+		return srcETag != "" && srcETag == dstETag, "", nil
+	}
+
+	// 非分片上传的ETag就是内容的MD5，相同时不需要读取内容
+	if srcETag != "" && srcETag == dstETag && !isMultipartETag(srcETag) {
+		return true, srcETag, nil
+	}
+	srcMD5, err := objectMD5(ctx, src, obj.Key)
+	if err != nil {
+		return false, "", fmt.Errorf("读取源对象失败: %w", err)
+	}
+	dstMD5, err := objectMD5(ctx, dst, obj.Key)
+	if err != nil {
+		return false, "", fmt.Errorf("读取目标对象失败: %w", err)
+	}
+	return srcMD5 == dstMD5, srcMD5, nil
+}
+
+// objectMD5 读取对象内容并计算MD5
+func objectMD5(ctx context.Context, svc StorageService, key string) (string, error) {
+	reader, _, err := svc.GetObject(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyObject 将对象从源后端下载到临时文件再上传到目标后端，返回内容的MD5
+func copyObject(ctx context.Context, src, dst StorageService, key string, tempDir string) (string, error) {
+	reader, _, err := src.GetObject(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("读取源对象失败: %w", err)
+	}
+	defer reader.Close()
+
+	// 临时文件保留对象的扩展名，各后端按本地文件的扩展名设置Content-Type
+	tempFile, err := os.CreateTemp(tempDir, "migrate-*"+path.Ext(key))
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tempFile, hash), reader); err != nil {
+		tempFile.Close()
+		return "", fmt.Errorf("下载源对象失败: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return "", fmt.Errorf("保存临时文件失败: %w", err)
+	}
+
+	if _, err := dst.UploadFile(ctx, key, tempFile.Name(), nil); err != nil {
+		return "", fmt.Errorf("上传到目标后端失败: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyMigration 列出目标后端的对象，检查每个已迁移的对象是否存在且大小一致
+// 按内容比较时还会读取目标对象校验MD5
+func verifyMigration(ctx context.Context, dst StorageService, objects []ObjectInfo, entries []MigrationEntry, opts MigrationOptions, report *MigrationReport) error {
+	listed, err := dst.ListObjects(ctx, opts.Prefix)
+	if err != nil {
+		return fmt.Errorf("列出目标对象失败: %w", err)
+	}
+	dstObjects := make(map[string]ObjectInfo, len(listed))
+	for _, obj := range listed {
+		dstObjects[obj.Key] = obj
+	}
+
+	for i, obj := range objects {
+		entry := entries[i]
+		if entry.State == MigrationFailed {
+			continue
+		}
+
+		dstObj, ok := dstObjects[obj.Key]
+		if !ok {
+			report.Mismatches = append(report.Mismatches, MigrationMismatch{Key: obj.Key, Reason: "目标后端不存在该对象"})
+			continue
+		}
+		if dstObj.Size != obj.Size {
+			report.Mismatches = append(report.Mismatches, MigrationMismatch{
+				Key:    obj.Key,
+				Reason: fmt.Sprintf("大小不一致: 源 %d, 目标 %d", obj.Size, dstObj.Size),
+			})
+			continue
+		}
+		if opts.Compare == CompareHash && entry.MD5 != "" {
+			dstMD5, err := objectMD5(ctx, dst, obj.Key)
+			if err != nil {
+				report.Mismatches = append(report.Mismatches, MigrationMismatch{Key: obj.Key, Reason: "读取目标对象失败: " + err.Error()})
+				continue
+			}
+			if dstMD5 != entry.MD5 {
+				report.Mismatches = append(report.Mismatches, MigrationMismatch{
+					Key:    obj.Key,
+					Reason: fmt.Sprintf("MD5不一致: 源 %s, 目标 %s", entry.MD5, dstMD5),
+				})
+				continue
+			}
+		}
+		report.Verified++
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package minio
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"go-uploader/storage"
+)
+
+// checkpointDir 分片上传断点记录的保存路径，与阿里云OSS的断点记录放在同一目录下
+const checkpointDir = "./checkpoint/minio"
+
+// checkpointPart 已完成的分片
+type checkpointPart struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// uploadCheckpoint 分片上传的断点记录
+// 记录上传ID和已完成的分片，再次上传同一个本地文件时从未完成的分片继续
+type uploadCheckpoint struct {
+	Endpoint  string           `json:"endpoint"` // 存储服务地址，多个后端使用同名存储桶时区分断点记录的归属
+	Bucket    string           `json:"bucket"`
+	Object    string           `json:"object"`
+	LocalFile string           `json:"localFile"`
+	FileSize  int64            `json:"fileSize"`
+	ModTime   time.Time        `json:"modTime"`
+	PartSize  int64            `json:"partSize"`
+	UploadID  string           `json:"uploadID"`
+	Parts     []checkpointPart `json:"parts"`
+	CreatedAt time.Time        `json:"createdAt"`
+
+	path string
+}
+
+// checkpointPath 返回断点记录文件的路径，由存储服务地址、存储桶、对象名和本地文件路径决定
+// 多副本同时把同一个本地文件上传到不同服务的同名存储桶时，各自使用独立的断点记录
+func checkpointPath(endpoint, bucket, object, localFile string) string {
+	if abs, err := filepath.Abs(localFile); err == nil {
+		localFile = abs
+	}
+	sum := sha1.Sum([]byte(endpoint + "\n" + bucket + "/" + object + "\n" + localFile))
+	return filepath.Join(checkpointDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadCheckpoint 读取断点记录，文件不存在时返回nil
+func loadCheckpoint(path string) (*uploadCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cp uploadCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	cp.path = path
+	return &cp, nil
+}
+
+// save 保存断点记录，先写临时文件再重命名，避免中途崩溃留下不完整的文件
+func (cp *uploadCheckpoint) save() error {
+	if err := os.MkdirAll(filepath.Dir(cp.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmpPath := cp.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, cp.path)
+}
+
+// remove 删除断点记录
+func (cp *uploadCheckpoint) remove() {
+	_ = os.Remove(cp.path)
+}
+
+// matches 返回断点记录是否对应同一个存储服务、文件和分片大小
+func (cp *uploadCheckpoint) matches(endpoint, bucket, object string, fileInfo os.FileInfo, partSize int64) bool {
+	return cp.Endpoint == endpoint && cp.Bucket == bucket && cp.Object == object && cp.UploadID != "" &&
+		cp.FileSize == fileInfo.Size() && cp.ModTime.Equal(fileInfo.ModTime()) &&
+		cp.PartSize == partSize
+}
+
+// partSizeOf 返回指定分片的大小
+func partSizeOf(partNumber int, partSize, fileSize int64) int64 {
+	offset := int64(partNumber-1) * partSize
+	return min(partSize, fileSize-offset)
+}
+
+// resumeCheckpoint 读取断点记录并与服务端的分片列表核对，记录无效时中止旧的分片上传并返回nil
+func (s *MinioService) resumeCheckpoint(ctx context.Context, core minio.Core, path, objectName string, fileInfo os.FileInfo, partSize int64) *uploadCheckpoint {
+	cp, err := loadCheckpoint(path)
+	if err != nil || cp == nil {
+		return nil
+	}
+
+	if !cp.matches(s.client.EndpointURL().Host, s.config.BucketName, objectName, fileInfo, partSize) {
+		// 文件或分片大小已变化，旧的分片无法复用
+		_ = core.AbortMultipartUpload(ctx, cp.Bucket, cp.Object, cp.UploadID)
+		cp.remove()
+		return nil
+	}
+
+	// 以服务端实际存在的分片为准，也能找回已上传但还没来得及写入断点记录的分片
+	var parts []checkpointPart
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(ctx, cp.Bucket, cp.Object, cp.UploadID, marker, 1000)
+		if err != nil {
+			// 分片上传已被中止或已过期
+			cp.remove()
+			return nil
+		}
+		for _, part := range result.ObjectParts {
+			if part.Size == partSizeOf(part.PartNumber, partSize, cp.FileSize) {
+				parts = append(parts, checkpointPart{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size})
+			}
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+	cp.Parts = parts
+
+	return cp
+}
+
+// uploadMultipart 带断点记录的分片上传
+// 每完成一个分片就更新断点记录，上传失败、暂停或服务重启后再次上传同一个本地文件时从未完成的分片继续
+func (s *MinioService) uploadMultipart(ctx context.Context, objectName, localFile string, fileInfo os.FileInfo,
+	contentType string, partSize int64, parallelism int, progressFn storage.ProgressCallback) (minio.UploadInfo, error) {
+	core := minio.Core{Client: s.client}
+	bucket := s.config.BucketName
+	fileSize := fileInfo.Size()
+
+	path := checkpointPath(s.client.EndpointURL().Host, bucket, objectName, localFile)
+	cp := s.resumeCheckpoint(ctx, core, path, objectName, fileInfo, partSize)
+	if cp == nil {
+		uploadID, err := core.NewMultipartUpload(ctx, bucket, objectName, minio.PutObjectOptions{ContentType: contentType})
+		if err != nil {
+			return minio.UploadInfo{}, fmt.Errorf("初始化分片上传失败: %w", err)
+		}
+		cp = &uploadCheckpoint{
+			Endpoint:  s.client.EndpointURL().Host,
+			Bucket:    bucket,
+			Object:    objectName,
+			LocalFile: localFile,
+			FileSize:  fileSize,
+			ModTime:   fileInfo.ModTime(),
+			PartSize:  partSize,
+			UploadID:  uploadID,
+			CreatedAt: time.Now(),
+			path:      path,
+		}
+	}
+	if err := cp.save(); err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("保存断点记录失败: %w", err)
+	}
+
+	file, err := os.Open(localFile)
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("无法打开文件: %w", err)
+	}
+	defer file.Close()
+
+	// 已完成的分片直接计入进度
+	done := make(map[int]bool, len(cp.Parts))
+	var doneBytes int64
+	for _, part := range cp.Parts {
+		done[part.PartNumber] = true
+		doneBytes += part.Size
+	}
+	progress := storage.NewPartProgress(fileSize, progressFn)
+	progress.Skip(doneBytes)
+
+	// 并行上传剩余的分片，任意分片失败时取消其余分片
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mutex    sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	limiter := storage.RateLimiterFrom(ctx)
+	partNumbers := make(chan int)
+	for i := 0; i < max(parallelism, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range partNumbers {
+				size := partSizeOf(partNumber, partSize, fileSize)
+				section := io.NewSectionReader(file, int64(partNumber-1)*partSize, size)
+				reader := progress.Reader(storage.NewThrottledReader(uploadCtx, section, limiter).(io.ReadSeeker))
+
+				part, err := core.PutObjectPart(uploadCtx, bucket, objectName, cp.UploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+
+				mutex.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("上传分片%d失败: %w", partNumber, err)
+						cancel()
+					}
+				} else {
+					cp.Parts = append(cp.Parts, checkpointPart{PartNumber: partNumber, ETag: part.ETag, Size: size})
+					if saveErr := cp.save(); saveErr != nil && firstErr == nil {
+						firstErr = fmt.Errorf("保存断点记录失败: %w", saveErr)
+						cancel()
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	totalParts := int((fileSize + partSize - 1) / partSize)
+feed:
+	for partNumber := 1; partNumber <= totalParts; partNumber++ {
+		if done[partNumber] {
+			continue
+		}
+		select {
+		case partNumbers <- partNumber:
+		case <-uploadCtx.Done():
+			break feed
+		}
+	}
+	close(partNumbers)
+	wg.Wait()
+
+	if firstErr != nil {
+		return minio.UploadInfo{}, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	// 按分片序号合并
+	sort.Slice(cp.Parts, func(i, j int) bool {
+		return cp.Parts[i].PartNumber < cp.Parts[j].PartNumber
+	})
+	completeParts := make([]minio.CompletePart, 0, len(cp.Parts))
+	for _, part := range cp.Parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	info, err := core.CompleteMultipartUpload(ctx, bucket, objectName, cp.UploadID, completeParts,
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("合并分片失败: %w", err)
+	}
+	info.Size = fileSize
+	cp.remove()
+
+	return info, nil
+}
+
+// AbortStaleUploads 中止超过指定时长仍未完成的分片上传，并删除对应的断点记录
+// 只处理本服务的断点记录中的上传ID，不会中止其他程序或其他后端在同一存储桶中发起的分片上传；
+// 断点记录在指定时长内有更新的分片上传仍在进行或等待恢复，不会被中止
+func (s *MinioService) AbortStaleUploads(ctx context.Context, maxAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-maxAge)
+
+	entries, err := os.ReadDir(checkpointDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取断点记录目录失败: %w", err)
+	}
+
+	core := minio.Core{Client: s.client}
+	endpoint := s.client.EndpointURL().Host
+	aborted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		cp, err := loadCheckpoint(filepath.Join(checkpointDir, entry.Name()))
+		if err != nil || cp == nil {
+			continue
+		}
+		// 旧版本的断点记录没有服务地址，按存储桶判断归属
+		if cp.Bucket != s.config.BucketName || (cp.Endpoint != "" && cp.Endpoint != endpoint) {
+			continue
+		}
+
+		if cp.UploadID != "" {
+			err := core.AbortMultipartUpload(ctx, cp.Bucket, cp.Object, cp.UploadID)
+			if err != nil && minio.ToErrorResponse(err).Code != "NoSuchUpload" {
+				return aborted, fmt.Errorf("中止分片上传失败: %s, %w", cp.Object, err)
+			}
+			if err == nil {
+				aborted++
+			}
+		}
+		cp.remove()
+	}
+
+	return aborted, nil
+}
@@ -0,0 +1,65 @@
+package minio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointPathSeparatesEndpoints(t *testing.T) {
+	a := checkpointPath("minio-a:9000", "uploads", "photos/a.jpg", "temp/a.jpg")
+	b := checkpointPath("minio-b:9000", "uploads", "photos/a.jpg", "temp/a.jpg")
+	if a == b {
+		t.Fatalf("不同存储服务的同名存储桶使用了相同的断点记录: %s", a)
+	}
+	if again := checkpointPath("minio-a:9000", "uploads", "photos/a.jpg", "temp/a.jpg"); again != a {
+		t.Errorf("同一个上传的断点记录路径不稳定: %s != %s", again, a)
+	}
+}
+
+func TestCheckpointMatches(t *testing.T) {
+	localFile := filepath.Join(t.TempDir(), "a.jpg")
+	if err := os.WriteFile(localFile, make([]byte, 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileInfo, err := os.Stat(localFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := uploadCheckpoint{
+		Endpoint: "minio-a:9000",
+		Bucket:   "uploads",
+		Object:   "photos/a.jpg",
+		FileSize: fileInfo.Size(),
+		ModTime:  fileInfo.ModTime(),
+		PartSize: 512,
+		UploadID: "upload-1",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(cp *uploadCheckpoint)
+		want   bool
+	}{
+		{"相同的上传", func(cp *uploadCheckpoint) {}, true},
+		{"其他存储服务", func(cp *uploadCheckpoint) { cp.Endpoint = "minio-b:9000" }, false},
+		{"没有记录存储服务", func(cp *uploadCheckpoint) { cp.Endpoint = "" }, false},
+		{"其他存储桶", func(cp *uploadCheckpoint) { cp.Bucket = "archive" }, false},
+		{"其他对象", func(cp *uploadCheckpoint) { cp.Object = "photos/b.jpg" }, false},
+		{"文件大小变化", func(cp *uploadCheckpoint) { cp.FileSize++ }, false},
+		{"文件修改时间变化", func(cp *uploadCheckpoint) { cp.ModTime = cp.ModTime.Add(time.Second) }, false},
+		{"分片大小变化", func(cp *uploadCheckpoint) { cp.PartSize = 1024 }, false},
+		{"没有上传ID", func(cp *uploadCheckpoint) { cp.UploadID = "" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := base
+			tt.modify(&cp)
+			if got := cp.matches("minio-a:9000", "uploads", "photos/a.jpg", fileInfo, 512); got != tt.want {
+				t.Errorf("matches() = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"strconv"
 
 	"github.com/joho/godotenv"
+
+	"go-uploader/storage"
 )
 
 // MinioConfig MinIO存储配置
@@ -16,6 +18,7 @@ type MinioConfig struct {
 	UseSSL          bool
 	BucketName      string
 	Region          string
+	Multipart       storage.MultipartConfig
 }
 
 // GetType 返回存储类型标识
@@ -29,30 +32,42 @@ func (c *MinioConfig) Validate() error {
 		c.SecretAccessKey == "" || c.BucketName == "" {
 		return fmt.Errorf("缺少必要的MinIO配置")
 	}
-	return nil
+	return c.Multipart.Validate()
 }
 
 // LoadMinioConfigFromEnv 从环境变量加载MinIO配置
 func LoadMinioConfigFromEnv() (*MinioConfig, error) {
+	return LoadMinioConfigFromEnvPrefix("MINIO")
+}
+
+// LoadMinioConfigFromEnvPrefix 从带指定前缀的环境变量加载MinIO配置，例如前缀 MINIO 对应 MINIO_ENDPOINT
+func LoadMinioConfigFromEnvPrefix(prefix string) (*MinioConfig, error) {
 	// 尝试加载.env文件，但不强制要求
 	_ = godotenv.Load()
 
 	// 读取SSL配置，默认为true
 	useSSL := true
-	if sslStr := os.Getenv("MINIO_USE_SSL"); sslStr != "" {
+	if sslStr := os.Getenv(prefix + "_USE_SSL"); sslStr != "" {
 		if boolVal, err := strconv.ParseBool(sslStr); err == nil {
 			useSSL = boolVal
 		}
 	}
 
 	config := &MinioConfig{
-		Endpoint:        os.Getenv("MINIO_ENDPOINT"),
-		AccessKeyID:     os.Getenv("MINIO_ACCESS_KEY_ID"),
-		SecretAccessKey: os.Getenv("MINIO_SECRET_ACCESS_KEY"),
+		Endpoint:        os.Getenv(prefix + "_ENDPOINT"),
+		AccessKeyID:     os.Getenv(prefix + "_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv(prefix + "_SECRET_ACCESS_KEY"),
 		UseSSL:          useSSL,
-		BucketName:      os.Getenv("MINIO_BUCKET_NAME"),
-		Region:          os.Getenv("MINIO_REGION"),
+		BucketName:      os.Getenv(prefix + "_BUCKET_NAME"),
+		Region:          os.Getenv(prefix + "_REGION"),
+	}
+
+	// 分片上传配置：<前缀>_PART_SIZE_MB、<前缀>_PARALLEL_NUM、<前缀>_ADAPTIVE_MULTIPART
+	multipart, err := storage.LoadMultipartConfigFromEnv(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("MinIO配置验证失败: %w", err)
 	}
+	config.Multipart = multipart
 
 	// 验证配置
 	if err := config.Validate(); err != nil {
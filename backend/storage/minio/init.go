@@ -17,4 +17,13 @@ func init() {
 
 	// 注册到全局工厂
 	storage.RegisterStorageFactory("minio", factory)
+
+	// 注册配置加载函数，用于按名称配置多个存储后端
+	storage.RegisterConfigLoader("minio", "MINIO", func(prefix string) (storage.StorageConfig, error) {
+		config, err := LoadMinioConfigFromEnvPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return config, nil
+	})
 }
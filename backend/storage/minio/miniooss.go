@@ -2,8 +2,10 @@ package minio
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -18,8 +20,10 @@ import (
 
 // MinioService MinIO存储服务实现
 type MinioService struct {
-	client *minio.Client
-	config *MinioConfig
+	client       *minio.Client
+	tuner        *storage.ParallelismTuner
+	config       *MinioConfig
+	bucketDomain string
 }
 
 // NewMinioService 创建新的MinIO存储服务
@@ -54,33 +58,18 @@ func NewMinioService(config *MinioConfig) (*MinioService, error) {
 		}
 	}
 
-	return &MinioService{
-		client: client,
-		config: config,
-	}, nil
+	return NewMinioServiceWithClient(client, config, ""), nil
 }
 
-// 创建一个自定义的读取器以支持进度回调
-type progressReader struct {
-	io.Reader
-	progressFn storage.ProgressCallback
-	total      int64
-	current    int64
-	lastUpdate int64
-}
-
-func (r *progressReader) Read(p []byte) (n int, err error) {
-	n, err = r.Reader.Read(p)
-	if n > 0 && r.progressFn != nil {
-		r.current += int64(n)
-		// 更新进度，但避免过于频繁的更新
-		if r.current-r.lastUpdate > r.total/100 || r.current == r.total {
-			increment := r.current - r.lastUpdate
-			r.progressFn(increment, r.current, r.total)
-			r.lastUpdate = r.current
-		}
+// NewMinioServiceWithClient 使用已创建的客户端创建存储服务，不检查也不创建存储桶
+// 供其他兼容S3协议的存储服务复用上传、断点续传和预签名的实现；bucketDomain 为空时按 Endpoint 生成路径风格的地址
+func NewMinioServiceWithClient(client *minio.Client, config *MinioConfig, bucketDomain string) *MinioService {
+	return &MinioService{
+		client:       client,
+		tuner:        storage.NewParallelismTuner(config.Multipart),
+		config:       config,
+		bucketDomain: bucketDomain,
 	}
-	return
 }
 
 // UploadFile 上传文件到MinIO
@@ -91,20 +80,6 @@ func (s *MinioService) UploadFile(ctx context.Context, objectName string, localF
 		return nil, fmt.Errorf("文件访问错误: %w", err)
 	}
 
-	// 打开文件
-	file, err := os.Open(localFile)
-	if err != nil {
-		return nil, fmt.Errorf("无法打开文件: %w", err)
-	}
-	defer file.Close()
-
-	// 创建进度读取器
-	reader := &progressReader{
-		Reader:     file,
-		progressFn: progressFn,
-		total:      fileInfo.Size(),
-	}
-
 	// 确保对象名称没有前导斜杠
 	objectName = strings.TrimPrefix(objectName, "/")
 
@@ -132,12 +107,36 @@ func (s *MinioService) UploadFile(ctx context.Context, objectName string, localF
 		}
 	}
 
-	// 上传文件
-	info, err := s.client.PutObject(ctx, s.config.BucketName, objectName, reader, fileInfo.Size(),
-		minio.PutObjectOptions{ContentType: contentType})
-	if err != nil {
-		return nil, fmt.Errorf("上传文件失败: %w", err)
+	// 按文件大小选择分片大小，并行数不超过上传调度器分配的配额
+	partSize := s.config.Multipart.PartSizeFor(fileInfo.Size())
+	parallelism := s.tuner.Parallelism(storage.PartParallelism(ctx))
+
+	startTime := time.Now()
+	var info minio.UploadInfo
+	if fileInfo.Size() > partSize {
+		// 大文件使用带断点记录的分片上传
+		info, err = s.uploadMultipart(ctx, objectName, localFile, fileInfo, contentType, partSize, parallelism, progressFn)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// 小文件直接上传
+		file, err := os.Open(localFile)
+		if err != nil {
+			return nil, fmt.Errorf("无法打开文件: %w", err)
+		}
+		defer file.Close()
+
+		// 创建进度读取器，上下文中指定了带宽限速器时按限速读取文件
+		reader := storage.NewProgressReader(ctx, storage.NewThrottledReader(ctx, file, storage.RateLimiterFrom(ctx)), fileInfo.Size(), progressFn)
+
+		info, err = s.client.PutObject(ctx, s.config.BucketName, objectName, reader, fileInfo.Size(),
+			minio.PutObjectOptions{ContentType: contentType})
+		if err != nil {
+			return nil, fmt.Errorf("上传文件失败: %w", err)
+		}
 	}
+	s.tuner.Observe(fileInfo.Size(), partSize, parallelism, time.Since(startTime))
 
 	return info, nil
 }
@@ -162,6 +161,86 @@ func (s *MinioService) IsObjectExist(ctx context.Context, objectName string) (bo
 	return true, nil // 对象存在
 }
 
+// StatObject 获取MinIO对象的元数据
+func (s *MinioService) StatObject(ctx context.Context, objectName string) (*storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	info, err := s.client.StatObject(ctx, s.config.BucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if errResp, ok := err.(minio.ErrorResponse); ok {
+			if errResp.Code == "NoSuchKey" || errResp.Code == "NotFound" {
+				return nil, storage.ErrObjectNotExists
+			}
+		}
+		return nil, fmt.Errorf("获取对象元数据失败: %w", err)
+	}
+
+	return &storage.ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}, nil
+}
+
+// ListObjects 列出MinIO中指定前缀下的所有对象
+func (s *MinioService) ListObjects(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	// 确保前缀没有前导斜杠
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	var objects []storage.ObjectInfo
+	for obj := range s.client.ListObjects(ctx, s.config.BucketName, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("列出对象失败: %w", obj.Err)
+		}
+		objects = append(objects, storage.ObjectInfo{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ContentType:  obj.ContentType,
+			ETag:         obj.ETag,
+			LastModified: obj.LastModified,
+		})
+	}
+
+	return objects, nil
+}
+
+// GetObject 读取MinIO对象内容
+func (s *MinioService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	obj, err := s.client.GetObject(ctx, s.config.BucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取对象失败: %w", err)
+	}
+
+	// GetObject是惰性请求，通过Stat确认对象存在并获取元数据
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		if errResp, ok := err.(minio.ErrorResponse); ok {
+			if errResp.Code == "NoSuchKey" || errResp.Code == "NotFound" {
+				return nil, nil, storage.ErrObjectNotExists
+			}
+		}
+		return nil, nil, fmt.Errorf("读取对象失败: %w", err)
+	}
+
+	return obj, &storage.ObjectInfo{
+		Key:          stat.Key,
+		Size:         stat.Size,
+		ContentType:  stat.ContentType,
+		ETag:         stat.ETag,
+		LastModified: stat.LastModified,
+	}, nil
+}
+
 // GeneratePresignedURL 生成预签名上传URL
 func (s *MinioService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
 	// 确保对象名称没有前导斜杠
@@ -187,7 +266,7 @@ func (s *MinioService) GeneratePresignedDownloadURL(ctx context.Context, objectN
 	// 设置请求参数，包括下载时的文件名
 	reqParams := make(url.Values)
 	fileName := filepath.Base(objectName)
-	reqParams.Set("response-content-disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	reqParams.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
 
 	// 生成预签名下载URL
 	presignedURL, err := s.client.PresignedGetObject(ctx, s.config.BucketName, objectName, expiration, reqParams)
@@ -203,6 +282,9 @@ func (s *MinioService) GeneratePresignedDownloadURL(ctx context.Context, objectN
 
 // GetBucketDomain 获取存储桶的域名
 func (s *MinioService) GetBucketDomain() string {
+	if s.bucketDomain != "" {
+		return s.bucketDomain
+	}
 	protocol := "http"
 	if s.config.UseSSL {
 		protocol = "https"
@@ -210,6 +292,31 @@ func (s *MinioService) GetBucketDomain() string {
 	return fmt.Sprintf("%s://%s/%s", protocol, s.config.Endpoint, s.config.BucketName)
 }
 
+// Probe 检查存储桶是否存在且可以访问，凭证失效时返回错误
+func (s *MinioService) Probe(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.config.BucketName)
+	if err != nil {
+		return fmt.Errorf("访问存储桶失败: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("存储桶不存在: %s", s.config.BucketName)
+	}
+	return nil
+}
+
+// IsRetryableError 按S3错误响应的状态码和错误码判断是否可以重试，其他错误按通用规则判断
+func (s *MinioService) IsRetryableError(err error) (retryable, known bool) {
+	var errResp minio.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.StatusCode == 0 {
+		return false, false
+	}
+	switch errResp.Code {
+	case "SlowDown", "RequestTimeout", "InternalError", "ServiceUnavailable", "XMinioServerNotInitialized":
+		return true, true
+	}
+	return storage.RetryableStatus(errResp.StatusCode), true
+}
+
 // MinioFactory MinIO服务工厂
 type MinioFactory struct{}
 
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 分片上传的限制，阿里云OSS和S3兼容存储相同
+const (
+	MaxUploadParts = 10000                  // 单个文件最多的分片数
+	MinPartSize    = 5 * 1024 * 1024        // 最小分片大小（最后一个分片除外）
+	MaxPartSize    = 5 * 1024 * 1024 * 1024 // 最大分片大小
+)
+
+// 自适应模式的参数
+const (
+	adaptiveTargetParts    = 1000 // 自适应模式下期望的分片数，分片过多会增加请求开销
+	adaptiveMaxParallelism = 16   // 自适应模式下的最大分片并行数
+	adaptiveMinSampleSize  = 2    // 至少包含的分片数，太小的文件无法反映并行上传的吞吐量
+	adaptiveThreshold      = 0.1  // 吞吐量变化超过10%才调整并行数
+)
+
+// MultipartConfig 分片上传配置
+type MultipartConfig struct {
+	PartSize    int64 // 分片大小（字节），自适应模式下作为最小分片大小
+	ParallelNum int   // 分片并行数，自适应模式下作为初始并行数
+	Adaptive    bool  // 自适应模式：按文件大小选择分片大小，按实际吞吐量调整并行数
+}
+
+// LoadMultipartConfigFromEnv 从带指定前缀的环境变量加载分片上传配置，例如 OSS_PART_SIZE_MB
+// 调用方负责加载.env文件
+func LoadMultipartConfigFromEnv(prefix string) (MultipartConfig, error) {
+	config := MultipartConfig{
+		PartSize:    MinPartSize,
+		ParallelNum: 3,
+	}
+
+	if value := os.Getenv(prefix + "_PART_SIZE_MB"); value != "" {
+		sizeMB, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || sizeMB <= 0 {
+			return config, fmt.Errorf("%s_PART_SIZE_MB 必须是正整数: %s", prefix, value)
+		}
+		config.PartSize = sizeMB * 1024 * 1024
+	}
+	if value := os.Getenv(prefix + "_PARALLEL_NUM"); value != "" {
+		parallelNum, err := strconv.Atoi(value)
+		if err != nil || parallelNum <= 0 {
+			return config, fmt.Errorf("%s_PARALLEL_NUM 必须是正整数: %s", prefix, value)
+		}
+		config.ParallelNum = parallelNum
+	}
+	if value := os.Getenv(prefix + "_ADAPTIVE_MULTIPART"); value != "" {
+		adaptive, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("%s_ADAPTIVE_MULTIPART 必须是布尔值: %s", prefix, value)
+		}
+		config.Adaptive = adaptive
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证分片上传配置
+func (c MultipartConfig) Validate() error {
+	if c.PartSize < MinPartSize || c.PartSize > MaxPartSize {
+		return fmt.Errorf("分片大小必须在5MB到5GB之间")
+	}
+	if c.ParallelNum <= 0 {
+		return fmt.Errorf("分片并行数必须大于0")
+	}
+	return nil
+}
+
+// PartSizeFor 返回指定大小的文件使用的分片大小
+// 自适应模式按文件大小放大分片以控制分片数；任何模式下都会放大分片以满足10,000个分片的上限
+func (c MultipartConfig) PartSizeFor(fileSize int64) int64 {
+	partSize := c.PartSize
+	if c.Adaptive {
+		partSize = max(partSize, ceilDiv(fileSize, adaptiveTargetParts))
+	}
+	partSize = max(partSize, ceilDiv(fileSize, MaxUploadParts))
+
+	// 向上取整到MB，便于阅读日志和断点记录
+	const mb = 1024 * 1024
+	partSize = ceilDiv(partSize, mb) * mb
+	return min(partSize, MaxPartSize)
+}
+
+// ceilDiv 向上取整的除法
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+// ParallelismTuner 根据实际吞吐量调整分片并行数
+// 每次上传结束后比较吞吐量：提升明显时增加并行数，下降明显时减少并行数，
+// 否则保持不变。并行数不会超过上传调度器分配的配额
+type ParallelismTuner struct {
+	mutex      sync.Mutex
+	config     MultipartConfig
+	current    int
+	throughput float64 // 上一次观测的吞吐量（字节/秒）
+	increasing bool    // 上一次调整的方向
+}
+
+// NewParallelismTuner 创建分片并行数调整器
+func NewParallelismTuner(config MultipartConfig) *ParallelismTuner {
+	return &ParallelismTuner{
+		config:     config,
+		current:    config.ParallelNum,
+		increasing: true,
+	}
+}
+
+// Parallelism 返回本次上传使用的分片并行数，granted 为上传调度器分配的配额（0表示不限）
+func (t *ParallelismTuner) Parallelism(granted int) int {
+	t.mutex.Lock()
+	parallelism := t.config.ParallelNum
+	if t.config.Adaptive {
+		parallelism = t.current
+	}
+	t.mutex.Unlock()
+
+	if granted > 0 && granted < parallelism {
+		parallelism = granted
+	}
+	return parallelism
+}
+
+// Observe 记录一次上传的吞吐量，自适应模式下据此调整并行数
+func (t *ParallelismTuner) Observe(size, partSize int64, parallelism int, elapsed time.Duration) {
+	if !t.config.Adaptive || elapsed <= 0 || size < partSize*adaptiveMinSampleSize {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// 只有使用了当前并行数的上传才能反映调整的效果
+	if parallelism != t.current {
+		return
+	}
+
+	throughput := float64(size) / elapsed.Seconds()
+	if t.throughput > 0 && throughput < t.throughput*(1-adaptiveThreshold) {
+		// 吞吐量下降，反向调整
+		t.increasing = !t.increasing
+	}
+	if t.throughput == 0 || throughput > t.throughput*(1+adaptiveThreshold) || throughput < t.throughput*(1-adaptiveThreshold) {
+		if t.increasing {
+			t.current = min(t.current+1, adaptiveMaxParallelism)
+		} else {
+			t.current = max(t.current-1, 1)
+		}
+	}
+	t.throughput = throughput
+}
@@ -0,0 +1,19 @@
+package storage
+
+import "context"
+
+// partParallelismKey 上下文中分片并行数的键
+type partParallelismKey struct{}
+
+// WithPartParallelism 在上下文中指定本次上传允许的分片并行数，由上传调度器按全局配额分配
+func WithPartParallelism(ctx context.Context, parallelism int) context.Context {
+	return context.WithValue(ctx, partParallelismKey{}, parallelism)
+}
+
+// PartParallelism 获取上下文中指定的分片并行数，未指定时返回0，由存储服务使用默认值
+func PartParallelism(ctx context.Context) int {
+	if parallelism, ok := ctx.Value(partParallelismKey{}).(int); ok && parallelism > 0 {
+		return parallelism
+	}
+	return 0
+}
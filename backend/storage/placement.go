@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// PlacementIndex 记录对象上传到的存储后端，读取请求未指定后端时按记录选择后端
+// 路由规则中的 size: 规则只在上传时能够匹配，按大小路由或上传时指定了后端的对象需要记录位置，
+// 下载、预签名下载和短链接才能找到对象。每次变化追加一行JSON，同一对象以最后一行为准
+type PlacementIndex struct {
+	mutex    sync.Mutex
+	path     string
+	file     *os.File
+	backends map[string]string
+}
+
+// placementEntry 位置记录中的一行，Backend 为空表示删除记录
+type placementEntry struct {
+	Key     string `json:"key"`
+	Backend string `json:"backend,omitempty"`
+}
+
+// OpenPlacementIndex 打开对象位置记录，文件不存在时创建
+// 打开时合并同一对象的多行记录，避免文件随重复上传无限增长
+func OpenPlacementIndex(path string) (*PlacementIndex, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建对象位置记录目录失败: %w", err)
+	}
+
+	backends := make(map[string]string)
+	lines := 0
+	file, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("打开对象位置记录失败: %w", err)
+	}
+	if err == nil {
+		// 中断时写了一半的最后一行直接忽略
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines++
+			var entry placementEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+				continue
+			}
+			if entry.Backend == "" {
+				delete(backends, entry.Key)
+			} else {
+				backends[entry.Key] = entry.Backend
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取对象位置记录失败: %w", err)
+		}
+	}
+
+	p := &PlacementIndex{path: path, backends: backends}
+	if lines > len(backends) {
+		if err := p.compact(); err != nil {
+			return nil, err
+		}
+	}
+	p.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开对象位置记录失败: %w", err)
+	}
+	return p, nil
+}
+
+// compact 将当前的记录写入临时文件后替换原文件，每个对象只保留一行
+func (p *PlacementIndex) compact() error {
+	tempPath := p.path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("合并对象位置记录失败: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for key, backend := range p.backends {
+		if err := encoder.Encode(placementEntry{Key: key, Backend: backend}); err != nil {
+			file.Close()
+			os.Remove(tempPath)
+			return fmt.Errorf("合并对象位置记录失败: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("合并对象位置记录失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("合并对象位置记录失败: %w", err)
+	}
+	if err := os.Rename(tempPath, p.path); err != nil {
+		return fmt.Errorf("合并对象位置记录失败: %w", err)
+	}
+	return nil
+}
+
+// Lookup 返回对象记录的存储后端，没有记录时返回false
+func (p *PlacementIndex) Lookup(objectName string) (string, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	backend, ok := p.backends[strings.TrimPrefix(objectName, "/")]
+	return backend, ok
+}
+
+// Record 记录对象所在的存储后端，backend 为空时删除记录；记录没有变化时不写文件
+func (p *PlacementIndex) Record(objectName, backend string) error {
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	current, ok := p.backends[objectName]
+	if (ok && current == backend) || (!ok && backend == "") {
+		return nil
+	}
+	data, err := json.Marshal(placementEntry{Key: objectName, Backend: backend})
+	if err != nil {
+		return err
+	}
+	if _, err := p.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("保存对象位置记录失败: %w", err)
+	}
+	if backend == "" {
+		delete(p.backends, objectName)
+	} else {
+		p.backends[objectName] = backend
+	}
+	return nil
+}
+
+// Close 关闭对象位置记录
+func (p *PlacementIndex) Close() error {
+	return p.file.Close()
+}
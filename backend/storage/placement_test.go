@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRouterLocatePlacedObjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "placements.jsonl")
+	newRouter := func() (*Router, *PlacementIndex) {
+		placements, err := OpenPlacementIndex(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		router := NewRouter()
+		for _, name := range []string{"cn-oss", "onprem-minio", "archive"} {
+			if err := router.Add(name, "minio", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := router.SetRules([]RoutingRule{
+			{Prefix: "archive/", Backend: "archive"},
+			{MinSize: 1024, Backend: "onprem-minio"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		router.SetPlacements(placements)
+		return router, placements
+	}
+
+	router, placements := newRouter()
+	// 上传时按大小路由到 onprem-minio，之后重新上传为小文件回到默认后端
+	for _, upload := range []struct {
+		objectName string
+		size       int64
+	}{
+		{"videos/a.mp4", 2048},
+		{"docs/b.txt", 2048},
+		{"docs/b.txt", 10},
+		{"archive/c.zip", 2048},
+	} {
+		name, _, err := router.Resolve("", upload.objectName, upload.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := router.Place(upload.objectName, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 上传时指定了后端
+	if err := router.Place("/notes/d.txt", "archive"); err != nil {
+		t.Fatal(err)
+	}
+	placements.Close()
+
+	// 重新打开时合并重复的记录
+	router, placements = newRouter()
+	defer placements.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("合并后的记录有%d行，期望2行:\n%s", lines, data)
+	}
+
+	tests := []struct {
+		name       string
+		backend    string
+		objectName string
+		want       string
+	}{
+		{"按大小路由的对象", "", "videos/a.mp4", "onprem-minio"},
+		{"重新上传后回到默认后端", "", "docs/b.txt", "cn-oss"},
+		{"按前缀路由的对象不需要记录", "", "archive/c.zip", "archive"},
+		{"上传时指定了后端", "", "notes/d.txt", "archive"},
+		{"没有记录的对象", "", "videos/e.mp4", "cn-oss"},
+		{"请求指定的后端优先", "cn-oss", "videos/a.mp4", "cn-oss"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := router.Locate(tt.backend, tt.objectName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Locate(%q, %q) = %s，期望 %s", tt.backend, tt.objectName, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// PartProgress 汇总并行上传的各个分片的进度
+// 每个分片通过Reader读取，SDK重试分片时回退读取位置，回退部分已计入的字节会被扣除，不会重复计算进度
+type PartProgress struct {
+	mutex       sync.Mutex
+	progressFn  ProgressCallback
+	total       int64
+	transferred int64
+	lastUpdate  int64
+}
+
+// NewPartProgress 创建分片上传的进度汇总，total为文件总大小
+func NewPartProgress(total int64, progressFn ProgressCallback) *PartProgress {
+	return &PartProgress{progressFn: progressFn, total: total}
+}
+
+// Skip 计入断点续传时已完成的字节数，增量记为0，避免被当作瞬时速度
+func (p *PartProgress) Skip(n int64) {
+	if p.progressFn == nil || n <= 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.transferred += n
+	p.lastUpdate = p.transferred
+	p.progressFn(0, p.transferred, p.total)
+}
+
+// Reader 返回读取单个分片并上报进度的读取器，支持Seek以便SDK重试
+func (p *PartProgress) Reader(r io.ReadSeeker) io.ReadSeeker {
+	return &partReader{ReadSeeker: r, progress: p}
+}
+
+// add 累加已上传的字节数，n为负数时扣除重试回退的字节数
+// 每上传约1%或全部完成时回调一次；回退后重新读取的字节在超过上次回调的进度之前不会回调，上报的进度不会倒退
+func (p *PartProgress) add(n int64) {
+	if p.progressFn == nil || n == 0 {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.transferred += n
+	if n > 0 && (p.transferred-p.lastUpdate > p.total/100 || p.transferred == p.total) {
+		p.progressFn(p.transferred-p.lastUpdate, p.transferred, p.total)
+		p.lastUpdate = p.transferred
+	}
+}
+
+// partReader 读取单个分片并上报进度
+type partReader struct {
+	io.ReadSeeker
+	progress *PartProgress
+	counted  int64 // 本分片已计入进度的字节数
+}
+
+// Read 读取数据并上报进度
+func (r *partReader) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	r.counted += int64(n)
+	r.progress.add(int64(n))
+	return n, err
+}
+
+// Seek 移动读取位置，回退到已计入进度的位置之前时扣除回退部分的字节数
+func (r *partReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.ReadSeeker.Seek(offset, whence)
+	if err == nil && pos < r.counted {
+		r.progress.add(pos - r.counted)
+		r.counted = pos
+	}
+	return pos, err
+}
+
+// ProgressReader 顺序读取文件时报告上传进度，上下文取消时停止读取
+type ProgressReader struct {
+	ctx        context.Context
+	reader     io.Reader
+	progressFn ProgressCallback
+	total      int64
+	current    int64
+	lastUpdate int64
+}
+
+// NewProgressReader 创建报告上传进度的读取器，total为要读取的总字节数，progressFn可以为nil
+func NewProgressReader(ctx context.Context, r io.Reader, total int64, progressFn ProgressCallback) *ProgressReader {
+	return &ProgressReader{ctx: ctx, reader: r, progressFn: progressFn, total: total}
+}
+
+// Read 读取数据，每读取约1%或全部读完时回调一次
+func (r *ProgressReader) Read(p []byte) (n int, err error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err = r.reader.Read(p)
+	if n > 0 && r.progressFn != nil {
+		r.current += int64(n)
+		// 更新进度，但避免过于频繁的更新
+		if r.current-r.lastUpdate > r.total/100 || r.current == r.total {
+			r.progressFn(r.current-r.lastUpdate, r.current, r.total)
+			r.lastUpdate = r.current
+		}
+	}
+	return
+}
+
+// Size 返回剩余的字节数，SFTP客户端据此决定并发写入的请求数
+func (r *ProgressReader) Size() int64 {
+	return r.total - r.current
+}
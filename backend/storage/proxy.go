@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxyURLFunc 生成由本服务代理下载对象的链接
+type ProxyURLFunc func(objectName string, expiration time.Duration) (string, error)
+
+// ProxyDownloader 可选接口，由不支持预签名下载的存储服务实现（例如WebDAV）
+// 创建存储后端时注入生成代理链接的函数，GeneratePresignedDownloadURL 返回指向本服务的链接，由本服务读取对象后转发
+type ProxyDownloader interface {
+	SetProxyURLFunc(fn ProxyURLFunc)
+}
+
+// ProxyLinkSigner 为代理下载链接签名和校验，签名覆盖后端名称、对象名和过期时间
+type ProxyLinkSigner struct {
+	secret  []byte
+	baseURL string
+}
+
+// NewProxyLinkSigner 创建代理下载链接签名器，baseURL 为本服务的访问地址，为空时生成以 / 开头的相对链接
+func NewProxyLinkSigner(secret []byte, baseURL string) *ProxyLinkSigner {
+	return &ProxyLinkSigner{
+		secret:  secret,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// sign 计算签名
+func (s *ProxyLinkSigner) sign(backend, objectName string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(backend + "\n" + objectName + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// URL 生成后端中对象的代理下载链接，格式为 /api/proxy/<后端>/<对象名>?expires=&signature=
+func (s *ProxyLinkSigner) URL(backend, objectName string, expiration time.Duration) string {
+	objectName = strings.TrimPrefix(objectName, "/")
+	expires := time.Now().Add(expiration).Unix()
+
+	segments := strings.Split(objectName, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(backend, objectName, expires))
+
+	return s.baseURL + "/api/proxy/" + url.PathEscape(backend) + "/" + strings.Join(segments, "/") + "?" + query.Encode()
+}
+
+// Verify 校验代理下载链接的签名和过期时间
+func (s *ProxyLinkSigner) Verify(backend, objectName, expires, signature string) error {
+	objectName = strings.TrimPrefix(objectName, "/")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrProxyLinkInvalid
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(backend, objectName, expiresAt))) {
+		return ErrProxyLinkInvalid
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrProxyLinkExpired
+	}
+	return nil
+}
@@ -0,0 +1,727 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 副本复制方式
+const (
+	ReplicationSync  = "sync"  // 上传时同步写入所有副本，任何副本失败都视为上传失败
+	ReplicationAsync = "async" // 写入主副本后立即返回，由后台队列写入其他副本
+)
+
+// 副本的复制状态
+const (
+	ReplicaPending    = "pending"    // 等待后台队列复制
+	ReplicaReplicated = "replicated" // 已复制
+	ReplicaFailed     = "failed"     // 复制失败且不再重试
+)
+
+// 复制记录和待复制文件的保存路径，每个复制后端使用以名称命名的子目录
+const (
+	replicationStatusDir = "./checkpoint/replication"
+	replicationSpoolDir  = "./temp/replication"
+)
+
+// replicationSweepInterval 检查到期的待复制副本的间隔
+const replicationSweepInterval = 30 * time.Second
+
+// ReplicationConfig 复制后端配置
+type ReplicationConfig struct {
+	Primary     string   // 主副本的后端名称
+	Secondaries []string // 其他副本的后端名称
+	Mode        string   // 复制方式：sync 或 async
+	Workers     int      // 后台复制的并发数
+	MaxAttempts int      // 后台复制每个副本最多尝试的次数
+}
+
+// LoadReplicationConfigFromEnv 从带指定前缀的环境变量加载复制后端配置，例如前缀 STORAGE_COMPLIANT
+// 对应 STORAGE_COMPLIANT_PRIMARY、STORAGE_COMPLIANT_REPLICAS（逗号分隔）、STORAGE_COMPLIANT_MODE
+// 调用方负责加载.env文件
+func LoadReplicationConfigFromEnv(prefix string) (ReplicationConfig, error) {
+	config := ReplicationConfig{
+		Primary:     strings.TrimSpace(os.Getenv(prefix + "_PRIMARY")),
+		Mode:        ReplicationAsync,
+		Workers:     2,
+		MaxAttempts: 5,
+	}
+	for _, name := range strings.Split(os.Getenv(prefix+"_REPLICAS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			config.Secondaries = append(config.Secondaries, name)
+		}
+	}
+	if value := os.Getenv(prefix + "_MODE"); value != "" {
+		config.Mode = value
+	}
+	if value := os.Getenv(prefix + "_WORKERS"); value != "" {
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers <= 0 {
+			return config, fmt.Errorf("%s_WORKERS 必须是正整数: %s", prefix, value)
+		}
+		config.Workers = workers
+	}
+	if value := os.Getenv(prefix + "_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts <= 0 {
+			return config, fmt.Errorf("%s_MAX_ATTEMPTS 必须是正整数: %s", prefix, value)
+		}
+		config.MaxAttempts = attempts
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证复制后端配置
+func (c ReplicationConfig) Validate() error {
+	if c.Primary == "" || len(c.Secondaries) == 0 {
+		return fmt.Errorf("复制后端需要配置主副本和至少一个其他副本")
+	}
+	seen := map[string]bool{c.Primary: true}
+	for _, name := range c.Secondaries {
+		if seen[name] {
+			return fmt.Errorf("副本重复: %s", name)
+		}
+		seen[name] = true
+	}
+	if c.Mode != ReplicationSync && c.Mode != ReplicationAsync {
+		return fmt.Errorf("复制方式必须是 sync 或 async: %s", c.Mode)
+	}
+	return nil
+}
+
+// HealthReporter 可选接口，由能够判断存储服务是否可用的装饰器实现（例如熔断器）
+type HealthReporter interface {
+	// Healthy 返回存储服务当前是否可用
+	Healthy() bool
+}
+
+// Replica 复制后端中的一个副本
+type Replica struct {
+	Name    string
+	Service StorageService
+}
+
+// healthy 返回副本当前是否可用，无法判断时视为可用
+func (r Replica) healthy() bool {
+	if reporter, ok := r.Service.(HealthReporter); ok {
+		return reporter.Healthy()
+	}
+	return true
+}
+
+// ReplicaStatus 对象在一个副本上的复制状态
+type ReplicaStatus struct {
+	State       string     `json:"state"`
+	Error       string     `json:"error,omitempty"`
+	Attempts    int        `json:"attempts"`
+	NextAttempt *time.Time `json:"nextAttempt,omitempty"` // 后台复制下一次尝试的时间，为空表示立即尝试
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// due 返回副本是否等待后台复制且已到下一次尝试的时间
+func (s *ReplicaStatus) due(now time.Time) bool {
+	return s.State == ReplicaPending && (s.NextAttempt == nil || !s.NextAttempt.After(now))
+}
+
+// ReplicationRecord 对象的复制记录，记录每个副本（不含主副本）的复制状态
+type ReplicationRecord struct {
+	ObjectName string                    `json:"objectName"`
+	Size       int64                     `json:"size"`
+	Primary    string                    `json:"primary"`
+	Replicas   map[string]*ReplicaStatus `json:"replicas"`
+	SpoolFile  string                    `json:"spoolFile,omitempty"` // 后台复制使用的本地文件，复制结束后删除
+	CreatedAt  time.Time                 `json:"createdAt"`
+	UpdatedAt  time.Time                 `json:"updatedAt"`
+}
+
+// Pending 返回是否还有等待后台复制的副本
+func (r *ReplicationRecord) Pending() bool {
+	for _, status := range r.Replicas {
+		if status.State == ReplicaPending {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplicatedService 将每次上传写入主副本和其他副本的组合存储服务
+// 读取时按顺序使用第一个有该对象的可用副本；每个对象在各副本上的复制状态保存在断点续传目录中，
+// 后台复制的任务在服务重启后继续执行
+type ReplicatedService struct {
+	name        string
+	primary     Replica
+	secondaries []Replica
+	config      ReplicationConfig
+	statusDir   string
+	spoolDir    string
+	logf        func(format string, args ...interface{})
+
+	mutex    sync.Mutex
+	pending  map[string]*ReplicationRecord // 还有待复制副本的记录
+	inFlight map[string]bool               // 正在复制的对象
+	queue    chan string
+}
+
+// NewReplicatedService 创建复制后端，加载未完成的后台复制任务并启动复制协程
+// logf 用于记录复制过程，可以为nil
+func NewReplicatedService(name string, primary Replica, secondaries []Replica, config ReplicationConfig, logf func(format string, args ...interface{})) (*ReplicatedService, error) {
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	s := &ReplicatedService{
+		name:        name,
+		primary:     primary,
+		secondaries: secondaries,
+		config:      config,
+		statusDir:   filepath.Join(replicationStatusDir, name),
+		spoolDir:    filepath.Join(replicationSpoolDir, name),
+		logf:        logf,
+		pending:     make(map[string]*ReplicationRecord),
+		inFlight:    make(map[string]bool),
+		queue:       make(chan string, 1024),
+	}
+
+	for _, dir := range []string{s.statusDir, s.spoolDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建复制目录失败: %w", err)
+		}
+	}
+
+	// 加载未完成的后台复制任务
+	entries, err := os.ReadDir(s.statusDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取复制记录目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		record, err := s.loadRecord(filepath.Join(s.statusDir, entry.Name()))
+		if err != nil {
+			logf("读取复制记录失败: %s, %v", entry.Name(), err)
+			continue
+		}
+		if record.Pending() {
+			s.pending[record.ObjectName] = record
+		}
+	}
+	if len(s.pending) > 0 {
+		logf("恢复%d个未完成的复制任务", len(s.pending))
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		go s.worker()
+	}
+	go s.sweeper()
+
+	return s, nil
+}
+
+// Replicas 按读取顺序返回所有副本的名称，第一个为主副本
+func (s *ReplicatedService) Replicas() []string {
+	names := []string{s.primary.Name}
+	for _, replica := range s.secondaries {
+		names = append(names, replica.Name)
+	}
+	return names
+}
+
+// ReplicationStatus 返回对象的复制记录，没有记录时返回 ErrObjectNotExists
+func (s *ReplicatedService) ReplicationStatus(objectName string) (*ReplicationRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, err := s.loadRecord(s.recordPath(objectName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotExists
+	}
+	return record, err
+}
+
+// UploadFile 上传文件到主副本，再同步或通过后台队列写入其他副本，进度只反映主副本的上传
+func (s *ReplicatedService) UploadFile(ctx context.Context, objectName string, localFile string, progressFn ProgressCallback) (interface{}, error) {
+	fileInfo, err := os.Stat(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("文件访问错误: %w", err)
+	}
+
+	result, err := s.primary.Service.UploadFile(ctx, objectName, localFile, progressFn)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &ReplicationRecord{
+		ObjectName: objectName,
+		Size:       fileInfo.Size(),
+		Primary:    s.primary.Name,
+		Replicas:   make(map[string]*ReplicaStatus, len(s.secondaries)),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if s.config.Mode == ReplicationSync {
+		err := s.replicateSync(ctx, record, localFile)
+		if err != nil {
+			// 主副本已经写入，重新上传时会因文件已存在而跳过，失败的副本只能由后台队列补齐
+			s.retryFailedInBackground(record, localFile)
+			return nil, err
+		}
+		if saveErr := s.saveRecord(record); saveErr != nil {
+			s.logf("保存复制记录失败: %s, %v", objectName, saveErr)
+		}
+		return result, nil
+	}
+
+	for _, replica := range s.secondaries {
+		record.Replicas[replica.Name] = &ReplicaStatus{State: ReplicaPending, UpdatedAt: now}
+	}
+	if err := s.schedule(record, localFile); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// schedule 保存待复制文件和复制记录，并将对象加入后台复制队列
+// 临时文件在上传结束后会被删除，先链接（或复制）到待复制目录；同一对象可能在上一次复制结束前再次上传，
+// 每条记录使用带随机后缀的独立待复制文件，新记录替换旧记录并删除旧记录的待复制文件
+func (s *ReplicatedService) schedule(record *ReplicationRecord, localFile string) error {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := strings.TrimSuffix(filepath.Base(s.recordPath(record.ObjectName)), ".json")
+	spoolFile := filepath.Join(s.spoolDir, name+"."+hex.EncodeToString(suffix)+".data")
+	if err := spool(localFile, spoolFile); err != nil {
+		return fmt.Errorf("保存待复制文件失败: %w", err)
+	}
+	record.SpoolFile = spoolFile
+
+	s.mutex.Lock()
+	err := s.saveRecord(record)
+	if err == nil {
+		if old, ok := s.pending[record.ObjectName]; ok {
+			s.removeSpool(old)
+		}
+		s.pending[record.ObjectName] = record
+	}
+	s.mutex.Unlock()
+	if err != nil {
+		_ = os.Remove(spoolFile)
+		return fmt.Errorf("保存复制记录失败: %w", err)
+	}
+
+	s.enqueue(record.ObjectName)
+	return nil
+}
+
+// removeSpool 删除记录的待复制文件
+func (s *ReplicatedService) removeSpool(record *ReplicationRecord) {
+	if record.SpoolFile == "" {
+		return
+	}
+	if err := os.Remove(record.SpoolFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.logf("删除待复制文件失败: %v", err)
+	}
+	record.SpoolFile = ""
+}
+
+// replicateSync 并行写入所有其他副本，返回第一个失败的错误
+func (s *ReplicatedService) replicateSync(ctx context.Context, record *ReplicationRecord, localFile string) error {
+	errs := make([]error, len(s.secondaries))
+	var wg sync.WaitGroup
+	for i, replica := range s.secondaries {
+		wg.Add(1)
+		go func(i int, replica Replica) {
+			defer wg.Done()
+			_, errs[i] = replica.Service.UploadFile(ctx, record.ObjectName, localFile, nil)
+		}(i, replica)
+	}
+	wg.Wait()
+
+	var firstErr error
+	for i, replica := range s.secondaries {
+		status := &ReplicaStatus{State: ReplicaReplicated, Attempts: 1, UpdatedAt: time.Now()}
+		if errs[i] != nil {
+			status.State = ReplicaFailed
+			status.Error = errs[i].Error()
+			s.logf("复制到副本失败: %s -> %s, %v", record.ObjectName, replica.Name, errs[i])
+			if firstErr == nil {
+				firstErr = fmt.Errorf("复制到副本 %s 失败: %w", replica.Name, errs[i])
+			}
+		}
+		record.Replicas[replica.Name] = status
+	}
+	record.UpdatedAt = time.Now()
+	return firstErr
+}
+
+// retryFailedInBackground 将同步复制失败的副本转入后台队列，按后台复制的规则推迟重试
+// 转入失败时保留失败状态，只保存复制记录
+func (s *ReplicatedService) retryFailedInBackground(record *ReplicationRecord, localFile string) {
+	var failed []*ReplicaStatus
+	for _, status := range record.Replicas {
+		if status.State == ReplicaFailed && status.Attempts < s.config.MaxAttempts {
+			nextAttempt := time.Now().Add(time.Duration(status.Attempts) * time.Minute)
+			status.State = ReplicaPending
+			status.NextAttempt = &nextAttempt
+			failed = append(failed, status)
+		}
+	}
+	if len(failed) == 0 {
+		if err := s.saveRecord(record); err != nil {
+			s.logf("保存复制记录失败: %s, %v", record.ObjectName, err)
+		}
+		return
+	}
+
+	if err := s.schedule(record, localFile); err != nil {
+		s.logf("失败的副本转入后台复制失败: %s, %v", record.ObjectName, err)
+		for _, status := range failed {
+			status.State = ReplicaFailed
+			status.NextAttempt = nil
+		}
+		if err := s.saveRecord(record); err != nil {
+			s.logf("保存复制记录失败: %s, %v", record.ObjectName, err)
+		}
+		return
+	}
+	s.logf("同步复制失败的%d个副本已转入后台队列重试: %s", len(failed), record.ObjectName)
+}
+
+// enqueue 将对象加入后台复制队列，队列已满时由定期检查重新加入
+func (s *ReplicatedService) enqueue(objectName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.inFlight[objectName] {
+		return
+	}
+	select {
+	case s.queue <- objectName:
+		s.inFlight[objectName] = true
+	default:
+	}
+}
+
+// sweeper 定期将到期的待复制副本重新加入队列
+func (s *ReplicatedService) sweeper() {
+	s.enqueueDue()
+	ticker := time.NewTicker(replicationSweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.enqueueDue()
+	}
+}
+
+// enqueueDue 将下一次尝试时间已到的对象加入队列
+func (s *ReplicatedService) enqueueDue() {
+	now := time.Now()
+	var due []string
+	s.mutex.Lock()
+	for objectName, record := range s.pending {
+		for _, status := range record.Replicas {
+			if status.due(now) {
+				due = append(due, objectName)
+				break
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, objectName := range due {
+		s.enqueue(objectName)
+	}
+}
+
+// worker 从队列中取出对象并写入待复制的副本
+func (s *ReplicatedService) worker() {
+	for objectName := range s.queue {
+		s.replicatePending(objectName)
+
+		s.mutex.Lock()
+		delete(s.inFlight, objectName)
+		s.mutex.Unlock()
+	}
+}
+
+// replicatePending 写入对象所有到期的待复制副本，失败时按尝试次数推迟下一次尝试
+// 复制期间对象被再次上传时，旧记录已被新记录替换，停止处理旧记录，由新记录负责复制
+func (s *ReplicatedService) replicatePending(objectName string) {
+	s.mutex.Lock()
+	record, ok := s.pending[objectName]
+	s.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	for _, replica := range s.secondaries {
+		s.mutex.Lock()
+		current := s.pending[objectName] == record
+		status := record.Replicas[replica.Name]
+		due := status != nil && status.due(time.Now())
+		spoolFile := record.SpoolFile
+		s.mutex.Unlock()
+		if !current {
+			return
+		}
+		if !due {
+			continue
+		}
+
+		_, err := replica.Service.UploadFile(context.Background(), objectName, spoolFile, nil)
+
+		s.mutex.Lock()
+		if s.pending[objectName] != record {
+			s.mutex.Unlock()
+			s.logf("复制期间对象被再次上传，放弃旧的复制任务: %s -> %s", objectName, replica.Name)
+			return
+		}
+		status.Attempts++
+		status.UpdatedAt = time.Now()
+		switch {
+		case err == nil:
+			status.State = ReplicaReplicated
+			status.Error = ""
+			status.NextAttempt = nil
+			s.logf("复制到副本完成: %s -> %s", objectName, replica.Name)
+		case status.Attempts >= s.config.MaxAttempts:
+			status.State = ReplicaFailed
+			status.Error = err.Error()
+			s.logf("复制到副本失败，不再重试: %s -> %s, %v", objectName, replica.Name, err)
+		default:
+			status.Error = err.Error()
+			delay := time.Duration(status.Attempts) * time.Minute
+			nextAttempt := time.Now().Add(delay)
+			status.NextAttempt = &nextAttempt
+			s.logf("复制到副本失败，%v后重试: %s -> %s, %v", delay, objectName, replica.Name, err)
+		}
+		record.UpdatedAt = time.Now()
+		if err := s.saveRecord(record); err != nil {
+			s.logf("保存复制记录失败: %s, %v", objectName, err)
+		}
+		s.mutex.Unlock()
+	}
+
+	// 所有副本都已结束时删除待复制文件
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.pending[objectName] == record && !record.Pending() {
+		delete(s.pending, objectName)
+		s.removeSpool(record)
+		if err := s.saveRecord(record); err != nil {
+			s.logf("保存复制记录失败: %s, %v", objectName, err)
+		}
+	}
+}
+
+// spool 将文件硬链接到目标路径，不支持硬链接时（例如跨文件系统）复制文件
+func spool(src, dst string) error {
+	_ = os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// recordPath 返回对象复制记录的路径
+func (s *ReplicatedService) recordPath(objectName string) string {
+	sum := sha1.Sum([]byte(objectName))
+	return filepath.Join(s.statusDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadRecord 读取复制记录
+func (s *ReplicatedService) loadRecord(path string) (*ReplicationRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var record ReplicationRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// saveRecord 保存复制记录，先写临时文件再重命名
+func (s *ReplicatedService) saveRecord(record *ReplicationRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.recordPath(record.ObjectName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// readOrder 返回读取时尝试副本的顺序：可用的副本在前，各自保持配置顺序
+func (s *ReplicatedService) readOrder() []Replica {
+	replicas := append([]Replica{s.primary}, s.secondaries...)
+	ordered := make([]Replica, 0, len(replicas))
+	var unhealthy []Replica
+	for _, replica := range replicas {
+		if replica.healthy() {
+			ordered = append(ordered, replica)
+		} else {
+			unhealthy = append(unhealthy, replica)
+		}
+	}
+	return append(ordered, unhealthy...)
+}
+
+// read 按顺序从副本读取：主副本可用时先读主副本，对象在副本上不存在、尚未复制到该副本或读取出错时尝试下一个副本
+// objectName 为空表示不针对单个对象（例如列出对象），不检查复制状态；所有副本都没有该对象时返回 ErrObjectNotExists
+func (s *ReplicatedService) read(objectName string, fn func(replica Replica) error) error {
+	var errs []error
+	for _, replica := range s.readOrder() {
+		if objectName != "" && !s.hasCopy(objectName, replica) {
+			continue
+		}
+		err := fn(replica)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrObjectNotExists) {
+			errs = append(errs, fmt.Errorf("%s: %w", replica.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return ErrObjectNotExists
+}
+
+// hasCopy 返回副本上是否可能有对象的当前版本：复制记录中等待复制或复制失败的副本没有，
+// 主副本和没有复制记录的对象（例如复制后端创建之前上传的对象）由实际读取判断
+func (s *ReplicatedService) hasCopy(objectName string, replica Replica) bool {
+	if replica.Name == s.primary.Name {
+		return true
+	}
+	record, err := s.ReplicationStatus(objectName)
+	if err != nil {
+		return true
+	}
+	status, ok := record.Replicas[replica.Name]
+	return !ok || status.State == ReplicaReplicated
+}
+
+// IsObjectExist 检查对象是否存在于任意一个副本
+func (s *ReplicatedService) IsObjectExist(ctx context.Context, objectName string) (bool, error) {
+	err := s.read(objectName, func(replica Replica) error {
+		exists, err := replica.Service.IsObjectExist(ctx, objectName)
+		if err == nil && !exists {
+			return ErrObjectNotExists
+		}
+		return err
+	})
+	if errors.Is(err, ErrObjectNotExists) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// StatObject 从第一个有该对象的可用副本获取对象元数据
+func (s *ReplicatedService) StatObject(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	var info *ObjectInfo
+	err := s.read(objectName, func(replica Replica) error {
+		var err error
+		info, err = replica.Service.StatObject(ctx, objectName)
+		return err
+	})
+	return info, err
+}
+
+// ListObjects 从第一个可用的副本列出对象
+func (s *ReplicatedService) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := s.read("", func(replica Replica) error {
+		var err error
+		objects, err = replica.Service.ListObjects(ctx, prefix)
+		return err
+	})
+	return objects, err
+}
+
+// GetObject 从第一个有该对象的可用副本读取对象内容
+func (s *ReplicatedService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error) {
+	var reader io.ReadCloser
+	var info *ObjectInfo
+	err := s.read(objectName, func(replica Replica) error {
+		var err error
+		reader, info, err = replica.Service.GetObject(ctx, objectName)
+		return err
+	})
+	return reader, info, err
+}
+
+// GeneratePresignedURL 复制后端不支持预签名上传：直传的文件只会写入主副本，不会复制到其他副本
+func (s *ReplicatedService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return "", nil, ErrPresignNotSupported
+}
+
+// GeneratePresignedDownloadURL 生成第一个有该对象的可用副本的预签名下载URL
+// 生成预签名URL不访问存储服务，先确认对象在该副本上存在，避免返回尚未复制完成的副本的链接
+func (s *ReplicatedService) GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	var url string
+	var headers map[string]string
+	err := s.read(objectName, func(replica Replica) error {
+		if _, err := replica.Service.StatObject(ctx, objectName); err != nil {
+			return err
+		}
+		var err error
+		url, headers, err = replica.Service.GeneratePresignedDownloadURL(ctx, objectName, expiration)
+		return err
+	})
+	return url, headers, err
+}
+
+// GetBucketDomain 返回主副本的存储桶域名
+func (s *ReplicatedService) GetBucketDomain() string {
+	return s.primary.Service.GetBucketDomain()
+}
+
+// Probe 检查主副本是否可用；同步复制时其他副本也必须可用，后台复制时其他副本的故障由队列重试
+func (s *ReplicatedService) Probe(ctx context.Context) error {
+	if err := s.primary.Service.Probe(ctx); err != nil {
+		return fmt.Errorf("%s: %w", s.primary.Name, err)
+	}
+	if s.config.Mode != ReplicationSync {
+		return nil
+	}
+	for _, replica := range s.secondaries {
+		if err := replica.Service.Probe(ctx); err != nil {
+			return fmt.Errorf("%s: %w", replica.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryService 保存在内存中的存储服务，用于测试装饰器和复制后端
+// err 不为nil时所有操作都返回该错误，unhealthy 模拟熔断中的后端
+type memoryService struct {
+	name      string
+	mutex     sync.Mutex
+	objects   map[string][]byte
+	err       error
+	unhealthy bool
+}
+
+func newMemoryService(name string) *memoryService {
+	return &memoryService{name: name, objects: make(map[string][]byte)}
+}
+
+func (m *memoryService) put(objectName, data string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.objects[objectName] = []byte(data)
+}
+
+func (m *memoryService) get(objectName string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	data, ok := m.objects[objectName]
+	if !ok {
+		return nil, ErrObjectNotExists
+	}
+	return data, nil
+}
+
+func (m *memoryService) UploadFile(ctx context.Context, objectName string, localFile string, progressFn ProgressCallback) (interface{}, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	data, err := os.ReadFile(localFile)
+	if err != nil {
+		return nil, err
+	}
+	m.put(objectName, string(data))
+	return nil, nil
+}
+
+func (m *memoryService) IsObjectExist(ctx context.Context, objectName string) (bool, error) {
+	_, err := m.get(objectName)
+	if errors.Is(err, ErrObjectNotExists) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (m *memoryService) StatObject(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	data, err := m.get(objectName)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Key: objectName, Size: int64(len(data))}, nil
+}
+
+func (m *memoryService) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	var objects []ObjectInfo
+	for key, data := range m.objects {
+		objects = append(objects, ObjectInfo{Key: key, Size: int64(len(data))})
+	}
+	return objects, nil
+}
+
+func (m *memoryService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error) {
+	data, err := m.get(objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), &ObjectInfo{Key: objectName, Size: int64(len(data))}, nil
+}
+
+func (m *memoryService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return "https://" + m.name + "/" + objectName, nil, m.err
+}
+
+// GeneratePresignedDownloadURL 与真实的存储服务一样只计算签名，不检查对象是否存在
+func (m *memoryService) GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return "https://" + m.name + "/" + objectName, nil, m.err
+}
+
+func (m *memoryService) GetBucketDomain() string {
+	return "https://" + m.name
+}
+
+func (m *memoryService) Probe(ctx context.Context) error {
+	return m.err
+}
+
+func (m *memoryService) Healthy() bool {
+	return !m.unhealthy
+}
+
+// newTestReplicatedService 创建不启动后台复制的复制后端，复制记录保存在临时目录中
+func newTestReplicatedService(t *testing.T) (*ReplicatedService, *memoryService, *memoryService) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	primary, secondary := newMemoryService("primary"), newMemoryService("secondary")
+	s, err := NewReplicatedService("both",
+		Replica{Name: "primary", Service: primary},
+		[]Replica{{Name: "secondary", Service: secondary}},
+		ReplicationConfig{Mode: ReplicationAsync, MaxAttempts: 3}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, primary, secondary
+}
+
+func readAll(t *testing.T, s StorageService, objectName string) (string, error) {
+	t.Helper()
+	reader, _, err := s.GetObject(context.Background(), objectName)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	return string(data), err
+}
+
+func TestReplicatedReadFallsThrough(t *testing.T) {
+	s, primary, secondary := newTestReplicatedService(t)
+	ctx := context.Background()
+
+	// 对象只在其他副本上，主副本返回不存在时继续查找
+	secondary.put("only-secondary.txt", "from secondary")
+	if data, err := readAll(t, s, "only-secondary.txt"); err != nil || data != "from secondary" {
+		t.Errorf("读取只在其他副本上的对象: %q, %v", data, err)
+	}
+	if exists, err := s.IsObjectExist(ctx, "only-secondary.txt"); err != nil || !exists {
+		t.Errorf("IsObjectExist = %v, %v，期望存在", exists, err)
+	}
+	url, _, err := s.GeneratePresignedDownloadURL(ctx, "only-secondary.txt", time.Hour)
+	if err != nil || url != "https://secondary/only-secondary.txt" {
+		t.Errorf("预签名下载URL: %s, %v", url, err)
+	}
+
+	// 两个副本都有时使用主副本
+	primary.put("both.txt", "from primary")
+	secondary.put("both.txt", "from secondary")
+	if data, err := readAll(t, s, "both.txt"); err != nil || data != "from primary" {
+		t.Errorf("读取两个副本都有的对象: %q, %v", data, err)
+	}
+
+	// 所有副本都没有
+	if _, err := readAll(t, s, "missing.txt"); !errors.Is(err, ErrObjectNotExists) {
+		t.Errorf("读取不存在的对象返回 %v，期望 ErrObjectNotExists", err)
+	}
+	if exists, err := s.IsObjectExist(ctx, "missing.txt"); err != nil || exists {
+		t.Errorf("IsObjectExist = %v, %v，期望不存在", exists, err)
+	}
+	if _, _, err := s.GeneratePresignedDownloadURL(ctx, "missing.txt", time.Hour); !errors.Is(err, ErrObjectNotExists) {
+		t.Errorf("不存在的对象的预签名下载返回 %v，期望 ErrObjectNotExists", err)
+	}
+
+	// 主副本故障时读取其他副本，其他副本也没有时返回主副本的错误而不是不存在
+	primary.err = errors.New("connection refused")
+	if data, err := readAll(t, s, "both.txt"); err != nil || data != "from secondary" {
+		t.Errorf("主副本故障时读取: %q, %v", data, err)
+	}
+	if _, err := readAll(t, s, "missing.txt"); err == nil || errors.Is(err, ErrObjectNotExists) {
+		t.Errorf("主副本故障时读取不存在的对象返回 %v，期望主副本的错误", err)
+	}
+}
+
+func TestReplicatedReadSkipsPendingReplicas(t *testing.T) {
+	s, primary, secondary := newTestReplicatedService(t)
+	ctx := context.Background()
+
+	// 其他副本上是旧版本，新版本还在等待后台复制；new.txt 上传前其他副本上没有
+	secondary.put("report.txt", "old version")
+	for objectName, data := range map[string]string{"report.txt": "new version", "new.txt": "new object"} {
+		localFile := filepath.Join(t.TempDir(), objectName)
+		if err := os.WriteFile(localFile, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UploadFile(ctx, objectName, localFile, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 主副本熔断时排在最后，但尚未复制完成的副本不会被使用
+	primary.unhealthy = true
+	if data, err := readAll(t, s, "report.txt"); err != nil || data != "new version" {
+		t.Errorf("读取等待复制的对象: %q, %v", data, err)
+	}
+	for _, objectName := range []string{"report.txt", "new.txt"} {
+		url, _, err := s.GeneratePresignedDownloadURL(ctx, objectName, time.Hour)
+		if err != nil || url != "https://primary/"+objectName {
+			t.Errorf("等待复制的对象的预签名下载URL: %s, %v", url, err)
+		}
+	}
+
+	// 复制完成后可以使用其他副本
+	s.replicatePending("report.txt")
+	if data, err := readAll(t, s, "report.txt"); err != nil || data != "new version" {
+		t.Errorf("复制完成后读取: %q, %v", data, err)
+	}
+	url, _, err := s.GeneratePresignedDownloadURL(ctx, "report.txt", time.Hour)
+	if err != nil || url != "https://secondary/report.txt" {
+		t.Errorf("复制完成后的预签名下载URL: %s, %v", url, err)
+	}
+}
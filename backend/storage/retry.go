@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// RetryConfig 存储服务重试配置
+type RetryConfig struct {
+	MaxAttempts    int           // 最多尝试的次数（包括第一次），1表示不重试
+	InitialBackoff time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff     time.Duration // 重试等待时间的上限
+}
+
+// LoadRetryConfigFromEnv 从环境变量加载存储服务重试配置
+// 调用方负责加载.env文件
+func LoadRetryConfigFromEnv() (RetryConfig, error) {
+	config := RetryConfig{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+	}
+
+	if value := os.Getenv("STORAGE_RETRY_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts <= 0 {
+			return config, fmt.Errorf("STORAGE_RETRY_MAX_ATTEMPTS 必须是正整数: %s", value)
+		}
+		config.MaxAttempts = attempts
+	}
+	if value := os.Getenv("STORAGE_RETRY_INITIAL_BACKOFF_MS"); value != "" {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 {
+			return config, fmt.Errorf("STORAGE_RETRY_INITIAL_BACKOFF_MS 必须是正整数: %s", value)
+		}
+		config.InitialBackoff = time.Duration(ms) * time.Millisecond
+	}
+	if value := os.Getenv("STORAGE_RETRY_MAX_BACKOFF_MS"); value != "" {
+		ms, err := strconv.Atoi(value)
+		if err != nil || ms <= 0 {
+			return config, fmt.Errorf("STORAGE_RETRY_MAX_BACKOFF_MS 必须是正整数: %s", value)
+		}
+		config.MaxBackoff = time.Duration(ms) * time.Millisecond
+	}
+
+	return config, config.Validate()
+}
+
+// Validate 验证重试配置
+func (c RetryConfig) Validate() error {
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("重试次数必须大于0")
+	}
+	if c.InitialBackoff <= 0 || c.MaxBackoff < c.InitialBackoff {
+		return fmt.Errorf("重试等待时间上限不能小于初始等待时间")
+	}
+	return nil
+}
+
+// backoff 返回第attempt次重试前的等待时间：指数增长并加入随机抖动，避免多个请求同时重试
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	delay := c.MaxBackoff
+	if shift := attempt - 1; shift < 30 {
+		delay = min(c.InitialBackoff<<shift, c.MaxBackoff)
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// ErrorClassifier 可选接口，由存储服务实现，用于识别SDK特有的错误类型
+type ErrorClassifier interface {
+	// IsRetryableError 返回错误是否可以重试；known为false时按通用规则判断
+	IsRetryableError(err error) (retryable, known bool)
+}
+
+// RetryableStatus 返回HTTP状态码是否表示可以重试的临时故障（超时、限流和服务端错误）
+func RetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// IsRetryableError 按通用规则判断错误是否为临时故障：超时、连接重置、连接中断以及可重试的HTTP状态码
+// 对象不存在、本地文件错误和主动取消不会重试；io.EOF 是正常的读取结束，也不会重试
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrObjectNotExists) || errors.Is(err, ErrObjectExists) || errors.Is(err, ErrInvalidConfig) {
+		return false
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return false
+	}
+
+	// 带HTTP状态码的错误，例如阿里云OSS的ServiceError
+	var statusErr interface{ HttpStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return RetryableStatus(statusErr.HttpStatusCode())
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// RetryEvent 一次重试的信息，用于记录日志和统计
+type RetryEvent struct {
+	Operation string        // 存储服务的方法名
+	Attempt   int           // 失败的是第几次尝试
+	Delay     time.Duration // 下一次尝试前的等待时间
+	Err       error         // 本次尝试的错误
+}
+
+// RetryStats 重试统计
+type RetryStats struct {
+	Retries      int64 `json:"retries"`      // 重试的总次数
+	Recovered    int64 `json:"recovered"`    // 重试后成功的操作数
+	Exhausted    int64 `json:"exhausted"`    // 重试次数或截止时间用尽后仍然失败的操作数
+	NonRetryable int64 `json:"nonRetryable"` // 因错误不可重试而直接失败的操作数
+}
+
+// RetryingService 为存储服务添加重试的装饰器
+// 临时故障按指数退避加随机抖动重试，等待时间超过上下文截止时间时不再重试
+type RetryingService struct {
+	inner      StorageService
+	config     RetryConfig
+	onRetry    func(RetryEvent)
+	classifier ErrorClassifier
+
+	retries      atomic.Int64
+	recovered    atomic.Int64
+	exhausted    atomic.Int64
+	nonRetryable atomic.Int64
+}
+
+// NewRetryingService 创建带重试的存储服务，onRetry 在每次重试前调用，可以为nil
+func NewRetryingService(inner StorageService, config RetryConfig, onRetry func(RetryEvent)) *RetryingService {
+	classifier, _ := inner.(ErrorClassifier)
+	return &RetryingService{
+		inner:      inner,
+		config:     config,
+		onRetry:    onRetry,
+		classifier: classifier,
+	}
+}
+
+// Unwrap 返回被装饰的存储服务
+func (s *RetryingService) Unwrap() StorageService {
+	return s.inner
+}
+
+// Stats 返回重试统计
+func (s *RetryingService) Stats() RetryStats {
+	return RetryStats{
+		Retries:      s.retries.Load(),
+		Recovered:    s.recovered.Load(),
+		Exhausted:    s.exhausted.Load(),
+		NonRetryable: s.nonRetryable.Load(),
+	}
+}
+
+// isRetryable 优先使用存储服务自身的错误分类
+func (s *RetryingService) isRetryable(err error) bool {
+	if s.classifier != nil {
+		if retryable, known := s.classifier.IsRetryableError(err); known {
+			return retryable
+		}
+	}
+	return IsRetryableError(err)
+}
+
+// do 执行操作，遇到临时故障时重试
+func (s *RetryingService) do(ctx context.Context, operation string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			if attempt > 1 {
+				s.recovered.Add(1)
+			}
+			return nil
+		}
+
+		// 上下文已结束（取消、暂停或超时）时直接返回
+		if ctx.Err() != nil {
+			return err
+		}
+		if !s.isRetryable(err) {
+			s.nonRetryable.Add(1)
+			return err
+		}
+		if s.config.MaxAttempts == 1 {
+			return err
+		}
+		if attempt >= s.config.MaxAttempts {
+			s.exhausted.Add(1)
+			return fmt.Errorf("%s 重试%d次后仍然失败: %w", operation, attempt-1, err)
+		}
+
+		delay := s.config.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			s.exhausted.Add(1)
+			return fmt.Errorf("%s 失败且剩余时间不足以重试: %w", operation, err)
+		}
+
+		s.retries.Add(1)
+		if s.onRetry != nil {
+			s.onRetry(RetryEvent{Operation: operation, Attempt: attempt, Delay: delay, Err: err})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// UploadFile 上传文件，失败后重新上传；支持断点续传的存储服务会从已完成的分片继续
+func (s *RetryingService) UploadFile(ctx context.Context, objectName string, localFile string, progressFn ProgressCallback) (interface{}, error) {
+	var result interface{}
+	err := s.do(ctx, "UploadFile", func() error {
+		var err error
+		result, err = s.inner.UploadFile(ctx, objectName, localFile, progressFn)
+		return err
+	})
+	return result, err
+}
+
+// IsObjectExist 检查对象是否存在
+func (s *RetryingService) IsObjectExist(ctx context.Context, objectName string) (bool, error) {
+	var exists bool
+	err := s.do(ctx, "IsObjectExist", func() error {
+		var err error
+		exists, err = s.inner.IsObjectExist(ctx, objectName)
+		return err
+	})
+	return exists, err
+}
+
+// StatObject 获取对象元数据，对象不存在时不重试
+func (s *RetryingService) StatObject(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	var info *ObjectInfo
+	err := s.do(ctx, "StatObject", func() error {
+		var err error
+		info, err = s.inner.StatObject(ctx, objectName)
+		return err
+	})
+	return info, err
+}
+
+// ListObjects 列出指定前缀下的所有对象
+func (s *RetryingService) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := s.do(ctx, "ListObjects", func() error {
+		var err error
+		objects, err = s.inner.ListObjects(ctx, prefix)
+		return err
+	})
+	return objects, err
+}
+
+// GetObject 读取对象内容，只重试打开对象，读取过程中的错误由调用方处理
+func (s *RetryingService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error) {
+	var reader io.ReadCloser
+	var info *ObjectInfo
+	err := s.do(ctx, "GetObject", func() error {
+		var err error
+		reader, info, err = s.inner.GetObject(ctx, objectName)
+		return err
+	})
+	return reader, info, err
+}
+
+// GeneratePresignedURL 生成预签名上传URL，签名在本地计算，不需要重试
+func (s *RetryingService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return s.inner.GeneratePresignedURL(ctx, objectName, expiration)
+}
+
+// GeneratePresignedDownloadURL 生成预签名下载URL，签名在本地计算，不需要重试
+func (s *RetryingService) GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return s.inner.GeneratePresignedDownloadURL(ctx, objectName, expiration)
+}
+
+// GetBucketDomain 获取存储桶的域名
+func (s *RetryingService) GetBucketDomain() string {
+	return s.inner.GetBucketDomain()
+}
+
+// Probe 检查存储服务是否可用，就绪检查需要及时反映故障，不重试
+func (s *RetryingService) Probe(ctx context.Context) error {
+	return s.inner.Probe(ctx)
+}
+
+// AbortStaleUploads 转发给被装饰的存储服务，不支持断点续传的存储服务返回0
+func (s *RetryingService) AbortStaleUploads(ctx context.Context, maxAge time.Duration) (int, error) {
+	if aborter, ok := s.inner.(StaleUploadAborter); ok {
+		return aborter.AbortStaleUploads(ctx, maxAge)
+	}
+	return 0, nil
+}
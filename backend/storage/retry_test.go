@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// statusError 带HTTP状态码的错误，与阿里云OSS的ServiceError一样实现 HttpStatusCode
+type statusError int
+
+func (e statusError) Error() string       { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) HttpStatusCode() int { return int(e) }
+
+// timeoutError 超时的网络错误
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// flakyService 按顺序返回预设错误的存储服务，预设错误用完后调用内存存储服务
+type flakyService struct {
+	*memoryService
+	errs  []error
+	calls int
+}
+
+func (f *flakyService) StatObject(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return f.memoryService.StatObject(ctx, objectName)
+}
+
+// classifyingService 由存储服务自身判断错误是否可以重试
+type classifyingService struct {
+	*flakyService
+}
+
+func (c classifyingService) IsRetryableError(err error) (retryable, known bool) {
+	if errors.Is(err, errSDKThrottled) {
+		return true, true
+	}
+	if errors.Is(err, errSDKFatal) {
+		return false, true
+	}
+	return false, false
+}
+
+// 存储服务SDK的错误：通用规则不会重试 errSDKThrottled，会重试 errSDKFatal，存储服务的分类与之相反
+var (
+	errSDKThrottled = errors.New("SlowDown")
+	errSDKFatal     = fmt.Errorf("QuotaExceeded: %w", syscall.ECONNRESET)
+)
+
+func TestBackoff(t *testing.T) {
+	config := RetryConfig{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration // 抖动前的等待时间，实际等待时间在它的一半到它本身之间
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{40, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if delay := config.backoff(tt.attempt); delay < tt.max/2 || delay > tt.max {
+				t.Fatalf("第%d次重试的等待时间为 %v，期望在 %v 到 %v 之间", tt.attempt, delay, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"没有错误", nil, false},
+		{"主动取消", fmt.Errorf("上传失败: %w", context.Canceled), false},
+		{"超时", fmt.Errorf("上传失败: %w", context.DeadlineExceeded), true},
+		{"连接超时", &net.OpError{Op: "dial", Err: timeoutError{}}, true},
+		{"读写超时", os.ErrDeadlineExceeded, true},
+		{"连接重置", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"连接被拒绝", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, true},
+		{"管道断开", fmt.Errorf("写入失败: %w", syscall.EPIPE), true},
+		{"连接中断", fmt.Errorf("读取响应失败: %w", io.ErrUnexpectedEOF), true},
+		{"正常的读取结束", io.EOF, false},
+		{"包装的读取结束", fmt.Errorf("读取失败: %w", io.EOF), false},
+		{"对象不存在", fmt.Errorf("%w: a.txt", ErrObjectNotExists), false},
+		{"对象已存在", ErrObjectExists, false},
+		{"配置错误", ErrInvalidConfig, false},
+		{"本地文件错误", &fs.PathError{Op: "open", Path: "temp/a.txt", Err: fs.ErrNotExist}, false},
+		{"限流", statusError(429), true},
+		{"服务不可用", fmt.Errorf("上传失败: %w", statusError(503)), true},
+		{"请求超时", statusError(408), true},
+		{"权限不足", statusError(403), false},
+		{"不存在", statusError(404), false},
+		{"其他错误", errors.New("invalid argument"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError(%v) = %v，期望 %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryingService(t *testing.T) {
+	reset := &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	tests := []struct {
+		name        string
+		maxAttempts int
+		errs        []error
+		classify    bool
+		wantCalls   int
+		wantErr     bool
+		wantStats   RetryStats
+	}{
+		{"第一次成功", 4, nil, false, 1, false, RetryStats{}},
+		{"重试后成功", 4, []error{reset, statusError(503)}, false, 3, false, RetryStats{Retries: 2, Recovered: 1}},
+		{"重试次数用尽", 3, []error{reset, reset, reset, reset}, false, 3, true, RetryStats{Retries: 2, Exhausted: 1}},
+		{"不可重试的错误", 4, []error{ErrObjectNotExists}, false, 1, true, RetryStats{NonRetryable: 1}},
+		{"读取结束不重试", 4, []error{io.EOF}, false, 1, true, RetryStats{NonRetryable: 1}},
+		{"不重试的配置", 1, []error{reset}, false, 1, true, RetryStats{}},
+		{"存储服务识别为可重试", 4, []error{errSDKThrottled}, true, 2, false, RetryStats{Retries: 1, Recovered: 1}},
+		{"存储服务识别为不可重试", 4, []error{errSDKFatal}, true, 1, true, RetryStats{NonRetryable: 1}},
+		{"存储服务无法识别时按通用规则", 4, []error{reset}, true, 2, false, RetryStats{Retries: 1, Recovered: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyService{memoryService: newMemoryService("flaky"), errs: tt.errs}
+			flaky.put("a.txt", "data")
+			var inner StorageService = flaky
+			if tt.classify {
+				inner = classifyingService{flaky}
+			}
+
+			var events []RetryEvent
+			config := RetryConfig{MaxAttempts: tt.maxAttempts, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+			s := NewRetryingService(inner, config, func(event RetryEvent) { events = append(events, event) })
+
+			_, err := s.StatObject(context.Background(), "a.txt")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("返回 %v，期望错误: %v", err, tt.wantErr)
+			}
+			if err != nil && len(tt.errs) > 0 && !errors.Is(err, tt.errs[0]) {
+				t.Errorf("返回的错误 %v 没有包装原始错误", err)
+			}
+			if flaky.calls != tt.wantCalls {
+				t.Errorf("调用了%d次，期望%d次", flaky.calls, tt.wantCalls)
+			}
+			if stats := s.Stats(); stats != tt.wantStats {
+				t.Errorf("统计为 %+v，期望 %+v", stats, tt.wantStats)
+			}
+			if len(events) != int(tt.wantStats.Retries) {
+				t.Errorf("重试回调调用了%d次，期望%d次", len(events), tt.wantStats.Retries)
+			}
+			for i, event := range events {
+				if event.Operation != "StatObject" || event.Attempt != i+1 {
+					t.Errorf("第%d次重试的信息不正确: %+v", i+1, event)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryingServiceRespectsContext(t *testing.T) {
+	reset := &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	// 剩余时间不足以等待下一次重试时直接失败
+	flaky := &flakyService{memoryService: newMemoryService("flaky"), errs: []error{reset, reset}}
+	s := NewRetryingService(flaky, RetryConfig{MaxAttempts: 4, InitialBackoff: time.Second, MaxBackoff: time.Second}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := s.StatObject(ctx, "a.txt"); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("返回 %v，期望连接重置的错误", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond || flaky.calls != 1 {
+		t.Errorf("剩余时间不足时仍然等待重试: 调用%d次, 用时 %v", flaky.calls, elapsed)
+	}
+	if stats := s.Stats(); stats.Exhausted != 1 {
+		t.Errorf("统计为 %+v，期望计入重试用尽", stats)
+	}
+
+	// 上下文已取消时不重试
+	flaky = &flakyService{memoryService: newMemoryService("flaky"), errs: []error{reset}}
+	s = NewRetryingService(flaky, RetryConfig{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}, nil)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.StatObject(ctx, "a.txt"); err == nil || flaky.calls != 1 {
+		t.Errorf("上下文已取消时返回 %v, 调用%d次", err, flaky.calls)
+	}
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BackendSpec 命名存储后端的定义
+type BackendSpec struct {
+	Name      string // 后端名称，例如 cn-oss
+	Type      string // 存储类型，例如 ali-oss、minio
+	EnvPrefix string // 配置的环境变量前缀，例如 cn-oss 对应 STORAGE_CN_OSS
+}
+
+// ParseBackendSpecs 解析命名存储后端列表
+// 格式为逗号分隔的 "名称=存储类型"，例如 "cn-oss=ali-oss,onprem-minio=minio"
+// 每个后端的配置从 STORAGE_<名称>_ 开头的环境变量读取，名称转为大写并将 - 替换为 _
+func ParseBackendSpecs(spec string) ([]BackendSpec, error) {
+	var specs []BackendSpec
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, storageType, ok := strings.Cut(item, "=")
+		name, storageType = strings.TrimSpace(name), strings.TrimSpace(storageType)
+		if !ok || name == "" || storageType == "" {
+			return nil, fmt.Errorf("存储后端格式应为 名称=存储类型: %s", item)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("存储后端名称重复: %s", name)
+		}
+		seen[name] = true
+
+		specs = append(specs, BackendSpec{
+			Name:      name,
+			Type:      storageType,
+			EnvPrefix: "STORAGE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")),
+		})
+	}
+	return specs, nil
+}
+
+// RoutingRule 未指定存储后端时按对象名前缀或文件大小选择后端的规则
+type RoutingRule struct {
+	Prefix  string // 对象名前缀，为空表示不限
+	MinSize int64  // 文件大小下限（字节），0表示不限
+	Backend string // 匹配时使用的后端名称
+}
+
+// matches 返回对象是否匹配规则，size 为0表示大小未知，不匹配按大小的规则
+func (r RoutingRule) matches(objectName string, size int64) bool {
+	if r.Prefix != "" && !strings.HasPrefix(objectName, r.Prefix) {
+		return false
+	}
+	if r.MinSize > 0 && size < r.MinSize {
+		return false
+	}
+	return true
+}
+
+// ParseRoutingRules 解析路由规则，按顺序匹配，第一条匹配的规则生效
+// 格式为逗号分隔的 "prefix:前缀=后端" 或 "size:最小MB=后端"，
+// 例如 "prefix:archive/=archive,size:1024=onprem-minio"
+func ParseRoutingRules(spec string) ([]RoutingRule, error) {
+	var rules []RoutingRule
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		condition, backend, ok := strings.Cut(item, "=")
+		backend = strings.TrimSpace(backend)
+		if !ok || backend == "" {
+			return nil, fmt.Errorf("缺少后端名称: %s", item)
+		}
+		kind, value, ok := strings.Cut(condition, ":")
+		if !ok {
+			return nil, fmt.Errorf("路由条件格式应为 prefix:前缀 或 size:最小MB: %s", item)
+		}
+
+		rule := RoutingRule{Backend: backend}
+		switch strings.TrimSpace(kind) {
+		case "prefix":
+			rule.Prefix = strings.TrimPrefix(strings.TrimSpace(value), "/")
+			if rule.Prefix == "" {
+				return nil, fmt.Errorf("前缀不能为空: %s", item)
+			}
+		case "size":
+			sizeMB, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || sizeMB <= 0 {
+				return nil, fmt.Errorf("文件大小必须是正整数 (MB): %s", item)
+			}
+			rule.MinSize = sizeMB * 1024 * 1024
+		default:
+			return nil, fmt.Errorf("不支持的路由条件 %s: %s", kind, item)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Router 管理多个命名存储后端，按请求指定的名称、对象位置记录或路由规则选择后端
+// 在启动时配置完成，之后只读，可以并发使用（对象位置记录自行加锁）
+type Router struct {
+	backends    map[string]StorageService
+	types       map[string]string
+	names       []string
+	defaultName string
+	rules       []RoutingRule
+	placements  *PlacementIndex
+}
+
+// NewRouter 创建存储后端路由
+func NewRouter() *Router {
+	return &Router{
+		backends: make(map[string]StorageService),
+		types:    make(map[string]string),
+	}
+}
+
+// Add 添加命名存储后端，第一个添加的后端为默认后端
+func (r *Router) Add(name, storageType string, service StorageService) error {
+	if _, exists := r.backends[name]; exists {
+		return fmt.Errorf("存储后端名称重复: %s", name)
+	}
+	r.backends[name] = service
+	r.types[name] = storageType
+	r.names = append(r.names, name)
+	if r.defaultName == "" {
+		r.defaultName = name
+	}
+	return nil
+}
+
+// SetDefault 设置未指定后端且没有匹配的路由规则时使用的后端
+func (r *Router) SetDefault(name string) error {
+	if _, exists := r.backends[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrStorageNotFound, name)
+	}
+	r.defaultName = name
+	return nil
+}
+
+// SetRules 设置路由规则，规则引用的后端必须已经添加
+func (r *Router) SetRules(rules []RoutingRule) error {
+	for _, rule := range rules {
+		if _, exists := r.backends[rule.Backend]; !exists {
+			return fmt.Errorf("%w: %s", ErrStorageNotFound, rule.Backend)
+		}
+	}
+	r.rules = rules
+	return nil
+}
+
+// SetPlacements 设置对象位置记录，读取请求未指定后端时先按记录选择后端
+func (r *Router) SetPlacements(placements *PlacementIndex) {
+	r.placements = placements
+}
+
+// Names 按添加顺序返回所有后端名称
+func (r *Router) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// DefaultName 返回默认后端名称
+func (r *Router) DefaultName() string {
+	return r.defaultName
+}
+
+// Type 返回后端的存储类型
+func (r *Router) Type(name string) string {
+	return r.types[name]
+}
+
+// Get 返回指定名称的后端，名称为空时返回默认后端
+func (r *Router) Get(name string) (StorageService, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+	service, exists := r.backends[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrStorageNotFound, name)
+	}
+	return service, nil
+}
+
+// Route 按路由规则为对象选择后端名称，size 为0表示大小未知，没有匹配的规则时返回默认后端
+func (r *Router) Route(objectName string, size int64) string {
+	objectName = strings.TrimPrefix(objectName, "/")
+	for _, rule := range r.rules {
+		if rule.matches(objectName, size) {
+			return rule.Backend
+		}
+	}
+	return r.defaultName
+}
+
+// Resolve 返回请求使用的后端：指定了名称时使用该后端，否则按路由规则选择
+func (r *Router) Resolve(name, objectName string, size int64) (string, StorageService, error) {
+	if name == "" {
+		name = r.Route(objectName, size)
+	}
+	service, err := r.Get(name)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, service, nil
+}
+
+// Locate 返回读取已有对象使用的后端：指定了名称时使用该后端，
+// 否则使用对象位置记录中的后端，没有记录时按对象名匹配路由规则
+func (r *Router) Locate(name, objectName string) (string, StorageService, error) {
+	if name == "" && r.placements != nil {
+		name, _ = r.placements.Lookup(objectName)
+	}
+	return r.Resolve(name, objectName, 0)
+}
+
+// Place 记录对象上传到的后端。只有与按对象名路由的结果不同时才需要记录
+// （例如按大小路由或上传时指定了后端），相同时删除之前的记录
+func (r *Router) Place(objectName, name string) error {
+	if r.placements == nil {
+		return nil
+	}
+	if name == r.Route(objectName, 0) {
+		name = ""
+	}
+	return r.placements.Record(objectName, name)
+}
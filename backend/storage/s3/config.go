@@ -0,0 +1,115 @@
+package s3
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"go-uploader/storage"
+)
+
+// 存储桶的寻址方式
+const (
+	AddressingAuto    = "auto"    // 由SDK按Endpoint判断，AWS S3等支持的服务使用虚拟主机方式
+	AddressingPath    = "path"    // 路径方式: https://endpoint/bucket/object，适用于MinIO、Ceph RGW
+	AddressingVirtual = "virtual" // 虚拟主机方式: https://bucket.endpoint/object，适用于腾讯云COS、AWS S3
+)
+
+// S3Config 通用S3兼容存储配置
+type S3Config struct {
+	Endpoint        string // 例如 s3.us-east-1.amazonaws.com、<账户ID>.r2.cloudflarestorage.com、cos.ap-guangzhou.myqcloud.com
+	Region          string // 例如 us-east-1，R2 使用 auto，留空时由SDK查询
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // 临时凭证（STS）的会话令牌，可选
+	BucketName      string
+	UseSSL          bool
+	AddressingStyle string // auto、path 或 virtual
+	CABundle        string // 自定义CA证书（PEM）文件路径，用于自签名证书的私有部署
+	PublicBaseURL   string // 文件访问地址的前缀，例如CDN域名，留空时按Endpoint和寻址方式生成
+	Multipart       storage.MultipartConfig
+}
+
+// GetType 返回存储类型标识
+func (c *S3Config) GetType() string {
+	return storage.TypeS3
+}
+
+// Validate 验证配置的合法性
+func (c *S3Config) Validate() error {
+	if c.Endpoint == "" || c.AccessKeyID == "" ||
+		c.SecretAccessKey == "" || c.BucketName == "" {
+		return fmt.Errorf("缺少必要的S3配置")
+	}
+	switch c.AddressingStyle {
+	case AddressingAuto, AddressingPath, AddressingVirtual:
+	default:
+		return fmt.Errorf("寻址方式必须是 auto、path 或 virtual: %s", c.AddressingStyle)
+	}
+	if c.PublicBaseURL != "" {
+		if u, err := url.Parse(c.PublicBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("公开访问地址必须是完整的URL: %s", c.PublicBaseURL)
+		}
+	}
+	return c.Multipart.Validate()
+}
+
+// LoadS3ConfigFromEnv 从环境变量加载S3配置
+func LoadS3ConfigFromEnv() (*S3Config, error) {
+	return LoadS3ConfigFromEnvPrefix("S3")
+}
+
+// LoadS3ConfigFromEnvPrefix 从带指定前缀的环境变量加载S3配置，例如前缀 S3 对应 S3_ENDPOINT
+func LoadS3ConfigFromEnvPrefix(prefix string) (*S3Config, error) {
+	// 尝试加载.env文件，但不强制要求
+	_ = godotenv.Load()
+
+	config := &S3Config{
+		Endpoint:        os.Getenv(prefix + "_ENDPOINT"),
+		Region:          os.Getenv(prefix + "_REGION"),
+		AccessKeyID:     os.Getenv(prefix + "_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv(prefix + "_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv(prefix + "_SESSION_TOKEN"),
+		BucketName:      os.Getenv(prefix + "_BUCKET_NAME"),
+		UseSSL:          true,
+		AddressingStyle: strings.ToLower(os.Getenv(prefix + "_ADDRESSING_STYLE")),
+		CABundle:        os.Getenv(prefix + "_CA_BUNDLE"),
+		PublicBaseURL:   strings.TrimSuffix(os.Getenv(prefix+"_PUBLIC_BASE_URL"), "/"),
+	}
+	if config.AddressingStyle == "" {
+		config.AddressingStyle = AddressingAuto
+	}
+
+	// Endpoint 可以写成完整的URL，协议决定是否使用SSL
+	if strings.Contains(config.Endpoint, "://") {
+		u, err := url.Parse(config.Endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("S3配置验证失败: Endpoint格式错误: %s", config.Endpoint)
+		}
+		config.Endpoint = u.Host
+		config.UseSSL = u.Scheme == "https"
+	}
+	if sslStr := os.Getenv(prefix + "_USE_SSL"); sslStr != "" {
+		if boolVal, err := strconv.ParseBool(sslStr); err == nil {
+			config.UseSSL = boolVal
+		}
+	}
+
+	// 分片上传配置：<前缀>_PART_SIZE_MB、<前缀>_PARALLEL_NUM、<前缀>_ADAPTIVE_MULTIPART
+	multipart, err := storage.LoadMultipartConfigFromEnv(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("S3配置验证失败: %w", err)
+	}
+	config.Multipart = multipart
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("S3配置验证失败: %w", err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,29 @@
+package s3
+
+import (
+	"go-uploader/storage"
+)
+
+// 在init函数中注册S3存储服务工厂
+func init() {
+	// 创建工厂函数
+	factory := func(config storage.StorageConfig) (storage.StorageService, error) {
+		s3Config, ok := config.(*S3Config)
+		if !ok {
+			return nil, storage.ErrInvalidConfig
+		}
+		return NewS3Service(s3Config)
+	}
+
+	// 注册到全局工厂
+	storage.RegisterStorageFactory(storage.TypeS3, factory)
+
+	// 注册配置加载函数，用于按名称配置多个存储后端
+	storage.RegisterConfigLoader(storage.TypeS3, "S3", func(prefix string) (storage.StorageConfig, error) {
+		config, err := LoadS3ConfigFromEnvPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return config, nil
+	})
+}
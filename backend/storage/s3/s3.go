@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+
+	miniostorage "go-uploader/storage/minio"
+)
+
+// S3Service 通用S3兼容存储服务实现
+// 上传、断点续传、预签名和重试分类复用MinIO存储服务的实现，区别在于客户端的寻址方式、凭证和证书可以配置，
+// 文件访问地址可以指定公开域名，并且不会自动创建存储桶
+type S3Service struct {
+	*miniostorage.MinioService
+}
+
+// NewS3Service 创建新的S3存储服务，存储桶必须已经存在
+func NewS3Service(config *S3Config) (*S3Service, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport, err := minio.DefaultTransport(config.UseSSL)
+	if err != nil {
+		return nil, fmt.Errorf("初始化S3客户端失败: %w", err)
+	}
+	if config.CABundle != "" {
+		rootCAs, err := loadCABundle(config.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		transport.TLSClientConfig.RootCAs = rootCAs
+	}
+
+	bucketLookup := minio.BucketLookupAuto
+	switch config.AddressingStyle {
+	case AddressingPath:
+		bucketLookup = minio.BucketLookupPath
+	case AddressingVirtual:
+		bucketLookup = minio.BucketLookupDNS
+	}
+
+	// 初始化S3客户端
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, config.SessionToken),
+		Secure:       config.UseSSL,
+		Region:       config.Region,
+		BucketLookup: bucketLookup,
+		Transport:    transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("初始化S3客户端失败: %w", err)
+	}
+
+	// 检查存储桶是否存在，云存储的存储桶通常由管理员创建，不自动创建
+	exists, err := client.BucketExists(context.Background(), config.BucketName)
+	if err != nil {
+		return nil, fmt.Errorf("检查存储桶失败: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("存储桶不存在: %s", config.BucketName)
+	}
+
+	minioConfig := &miniostorage.MinioConfig{
+		Endpoint:        config.Endpoint,
+		AccessKeyID:     config.AccessKeyID,
+		SecretAccessKey: config.SecretAccessKey,
+		UseSSL:          config.UseSSL,
+		BucketName:      config.BucketName,
+		Region:          config.Region,
+		Multipart:       config.Multipart,
+	}
+	return &S3Service{
+		MinioService: miniostorage.NewMinioServiceWithClient(client, minioConfig, bucketDomain(client, config)),
+	}, nil
+}
+
+// loadCABundle 读取PEM格式的CA证书，与系统证书一起作为可信的根证书
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取CA证书失败: %w", err)
+	}
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil || rootCAs == nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA证书文件中没有有效的证书: %s", path)
+	}
+	return rootCAs, nil
+}
+
+// bucketDomain 返回文件访问地址的前缀：优先使用公开访问地址，否则按寻址方式由Endpoint生成
+func bucketDomain(client *minio.Client, config *S3Config) string {
+	if config.PublicBaseURL != "" {
+		return config.PublicBaseURL
+	}
+
+	protocol := "http"
+	if config.UseSSL {
+		protocol = "https"
+	}
+	virtual := config.AddressingStyle == AddressingVirtual ||
+		(config.AddressingStyle == AddressingAuto && s3utils.IsVirtualHostSupported(*client.EndpointURL(), config.BucketName))
+	if virtual {
+		return fmt.Sprintf("%s://%s.%s", protocol, config.BucketName, config.Endpoint)
+	}
+	return fmt.Sprintf("%s://%s/%s", protocol, config.Endpoint, config.BucketName)
+}
@@ -0,0 +1,159 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"go-uploader/storage"
+)
+
+// newTestService 使用 S3_TEST_ 前缀的环境变量连接S3兼容存储（例如本地的MinIO），未配置时跳过测试
+// 存储桶需要预先创建，测试上传的对象放在 go-uploader-test/ 下，不会自动删除，请使用专门的测试存储桶
+//
+//	S3_TEST_ENDPOINT=127.0.0.1:9000 S3_TEST_ACCESS_KEY_ID=minioadmin S3_TEST_SECRET_ACCESS_KEY=minioadmin \
+//	S3_TEST_BUCKET_NAME=uploads S3_TEST_USE_SSL=false S3_TEST_ADDRESSING_STYLE=path go test ./storage/s3/
+func newTestService(t *testing.T) (*S3Service, *S3Config) {
+	t.Helper()
+
+	if os.Getenv("S3_TEST_ENDPOINT") == "" {
+		t.Skip("未设置 S3_TEST_ENDPOINT，跳过S3存储测试")
+	}
+	config, err := LoadS3ConfigFromEnvPrefix("S3_TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewS3Service(config)
+	if err != nil {
+		t.Fatalf("创建S3存储服务失败: %v", err)
+	}
+
+	// 分片上传的断点记录保存在当前目录下，切换到临时目录避免写入源码目录
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return s, config
+}
+
+// writeTempFile 在临时目录中创建内容为data的文件
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	localFile := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(localFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return localFile
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, config := newTestService(t)
+	ctx := context.Background()
+	prefix := fmt.Sprintf("go-uploader-test/%d/", time.Now().UnixNano())
+
+	// 超过分片大小的文件走分片上传
+	large := make([]byte, config.Multipart.PartSizeFor(0)+1024)
+	rand.Read(large)
+	files := map[string][]byte{
+		prefix + "docs/report 2024.txt": []byte("quarterly report"),
+		prefix + "docs/large.bin":       large,
+		prefix + "docs2/other.txt":      []byte("not under docs/"),
+	}
+	for objectName, data := range files {
+		var transferred int64
+		progressFn := func(increment, current, total int64) { transferred = current }
+		if _, err := s.UploadFile(ctx, objectName, writeTempFile(t, filepath.Base(objectName), data), progressFn); err != nil {
+			t.Fatalf("上传 %s 失败: %v", objectName, err)
+		}
+		if transferred != int64(len(data)) {
+			t.Errorf("上传 %s 的进度为 %d，期望 %d", objectName, transferred, len(data))
+		}
+	}
+
+	info, err := s.StatObject(ctx, prefix+"docs/large.bin")
+	if err != nil {
+		t.Fatalf("获取元数据失败: %v", err)
+	}
+	if info.Size != int64(len(large)) {
+		t.Errorf("元数据中的大小为 %d，期望 %d", info.Size, len(large))
+	}
+	if _, err := s.StatObject(ctx, prefix+"docs/missing.txt"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("不存在的对象返回 %v，期望 ErrObjectNotExists", err)
+	}
+
+	objects, err := s.ListObjects(ctx, prefix+"docs/")
+	if err != nil {
+		t.Fatalf("列出对象失败: %v", err)
+	}
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != prefix+"docs/large.bin" || keys[1] != prefix+"docs/report 2024.txt" {
+		t.Errorf("列出的对象为 %v", keys)
+	}
+
+	reader, _, err := s.GetObject(ctx, prefix+"docs/large.bin")
+	if err != nil {
+		t.Fatalf("读取对象失败: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(data, large) {
+		t.Errorf("读取的内容与上传的不一致: %d 字节, %v", len(data), err)
+	}
+}
+
+func TestPresign(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+	objectName := fmt.Sprintf("go-uploader-test/%d/presigned.txt", time.Now().UnixNano())
+
+	uploadURL, headers, err := s.GeneratePresignedURL(ctx, objectName, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("生成预签名上传URL失败: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader([]byte("presigned upload")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("预签名上传失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("预签名上传返回 %s", resp.Status)
+	}
+
+	downloadURL, _, err := s.GeneratePresignedDownloadURL(ctx, objectName, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("生成预签名下载URL失败: %v", err)
+	}
+	resp, err = http.Get(downloadURL)
+	if err != nil {
+		t.Fatalf("预签名下载失败: %v", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != "presigned upload" {
+		t.Errorf("预签名下载返回 %s: %q, %v", resp.Status, data, err)
+	}
+}
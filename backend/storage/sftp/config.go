@@ -0,0 +1,124 @@
+package sftp
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"go-uploader/storage"
+)
+
+// SFTPConfig SFTP存储配置
+type SFTPConfig struct {
+	Host                 string        // 服务器地址，可以带端口，例如 lab-nas.internal:2222
+	Port                 int           // 服务器端口，Host中没有端口时使用，默认22
+	Username             string        // 登录用户名
+	Password             string        // 密码认证，与私钥至少配置一个
+	PrivateKey           string        // 私钥文件路径
+	PrivateKeyPassphrase string        // 私钥的密码，私钥未加密时留空
+	KnownHosts           string        // known_hosts文件路径，默认 ~/.ssh/known_hosts
+	HostKeyFingerprint   string        // 服务器公钥的SHA256指纹（ssh-keygen -lf 的输出），配置后不再读取known_hosts
+	Root                 string        // 存放文件的目录，相对路径相对于登录用户的主目录
+	Timeout              time.Duration // 建立连接的超时时间
+}
+
+// GetType 返回存储类型标识
+func (c *SFTPConfig) GetType() string {
+	return storage.TypeSFTP
+}
+
+// Validate 验证配置的合法性
+func (c *SFTPConfig) Validate() error {
+	if c.Host == "" || c.Username == "" {
+		return fmt.Errorf("缺少必要的SFTP配置")
+	}
+	if c.Password == "" && c.PrivateKey == "" {
+		return fmt.Errorf("SFTP需要配置密码或私钥")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("SFTP端口无效: %d", c.Port)
+	}
+	if c.HostKeyFingerprint == "" && c.KnownHosts == "" {
+		return fmt.Errorf("SFTP需要配置known_hosts文件或服务器公钥指纹")
+	}
+	if c.HostKeyFingerprint != "" && !strings.HasPrefix(c.HostKeyFingerprint, "SHA256:") {
+		return fmt.Errorf("SFTP服务器公钥指纹必须是SHA256格式，例如 SHA256:xxxx")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("SFTP连接超时时间必须大于0")
+	}
+	return nil
+}
+
+// Addr 返回 主机:端口 形式的服务器地址
+func (c *SFTPConfig) Addr() string {
+	if _, _, err := net.SplitHostPort(c.Host); err == nil {
+		return c.Host
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// LoadSFTPConfigFromEnv 从环境变量加载SFTP配置
+func LoadSFTPConfigFromEnv() (*SFTPConfig, error) {
+	return LoadSFTPConfigFromEnvPrefix("SFTP")
+}
+
+// LoadSFTPConfigFromEnvPrefix 从带指定前缀的环境变量加载SFTP配置，例如前缀 SFTP 对应 SFTP_HOST
+func LoadSFTPConfigFromEnvPrefix(prefix string) (*SFTPConfig, error) {
+	// 尝试加载.env文件，但不强制要求
+	_ = godotenv.Load()
+
+	config := &SFTPConfig{
+		Host:                 os.Getenv(prefix + "_HOST"),
+		Port:                 22,
+		Username:             os.Getenv(prefix + "_USERNAME"),
+		Password:             os.Getenv(prefix + "_PASSWORD"),
+		PrivateKey:           os.Getenv(prefix + "_PRIVATE_KEY"),
+		PrivateKeyPassphrase: os.Getenv(prefix + "_PRIVATE_KEY_PASSPHRASE"),
+		KnownHosts:           os.Getenv(prefix + "_KNOWN_HOSTS"),
+		HostKeyFingerprint:   os.Getenv(prefix + "_HOST_KEY_FINGERPRINT"),
+		Root:                 os.Getenv(prefix + "_ROOT"),
+		Timeout:              30 * time.Second,
+	}
+	if value := os.Getenv(prefix + "_PORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("SFTP配置验证失败: %s_PORT 必须是整数: %s", prefix, value)
+		}
+		config.Port = port
+	}
+	if value := os.Getenv(prefix + "_TIMEOUT_SECONDS"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("SFTP配置验证失败: %s_TIMEOUT_SECONDS 必须是正整数: %s", prefix, value)
+		}
+		config.Timeout = time.Duration(seconds) * time.Second
+	}
+	// 根目录 / 本身不能去掉结尾的斜杠，否则会变成用户主目录
+	if len(config.Root) > 1 {
+		config.Root = strings.TrimSuffix(config.Root, "/")
+	}
+	if config.Root == "" {
+		config.Root = "."
+	}
+
+	// 未配置指纹和known_hosts时使用当前用户的known_hosts
+	if config.HostKeyFingerprint == "" && config.KnownHosts == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			config.KnownHosts = filepath.Join(home, ".ssh", "known_hosts")
+		}
+	}
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("SFTP配置验证失败: %w", err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,29 @@
+package sftp
+
+import (
+	"go-uploader/storage"
+)
+
+// 在init函数中注册SFTP存储服务工厂
+func init() {
+	// 创建工厂函数
+	factory := func(config storage.StorageConfig) (storage.StorageService, error) {
+		sftpConfig, ok := config.(*SFTPConfig)
+		if !ok {
+			return nil, storage.ErrInvalidConfig
+		}
+		return NewSFTPService(sftpConfig)
+	}
+
+	// 注册到全局工厂
+	storage.RegisterStorageFactory(storage.TypeSFTP, factory)
+
+	// 注册配置加载函数，用于按名称配置多个存储后端
+	storage.RegisterConfigLoader(storage.TypeSFTP, "SFTP", func(prefix string) (storage.StorageConfig, error) {
+		config, err := LoadSFTPConfigFromEnvPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return config, nil
+	})
+}
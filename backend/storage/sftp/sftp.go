@@ -0,0 +1,529 @@
+package sftp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	sftpclient "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"go-uploader/storage"
+)
+
+// 上传过程中的临时文件名为 .<文件名>.<随机串>.uploading，列出文件时会被跳过
+const tempFileSuffix = ".uploading"
+
+// keepaliveInterval 空闲连接发送心跳的间隔，心跳失败时关闭连接，下次操作重新连接
+const keepaliveInterval = 30 * time.Second
+
+// SFTPService SFTP存储服务实现
+// 所有操作共用一个SSH连接，连接断开后在下一次操作时重新建立
+// SFTP请求本身不支持取消，上下文结束时关闭连接，使阻塞的操作立即返回
+// SFTP没有预签名URL，下载链接由本服务代理（见 storage.ProxyDownloader），不支持预签名直传
+type SFTPService struct {
+	config    *SFTPConfig
+	sshConfig *ssh.ClientConfig
+	root      string // 存放文件的目录的绝对路径
+	proxyURL  storage.ProxyURLFunc
+
+	mu        sync.Mutex
+	client    *sftpclient.Client
+	sshClient *ssh.Client
+}
+
+// NewSFTPService 创建新的SFTP存储服务
+func NewSFTPService(config *SFTPConfig) (*SFTPService, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	sshConfig, err := newSSHClientConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SFTPService{
+		config:    config,
+		sshConfig: sshConfig,
+	}
+
+	// 连接服务器，并将存放文件的目录解析为绝对路径
+	client, _, err := s.connect(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	root, err := client.RealPath(config.Root)
+	if err != nil {
+		return nil, fmt.Errorf("解析SFTP目录 %s 失败: %w", config.Root, err)
+	}
+	s.root = root
+
+	// 检查目录是否存在且可以访问
+	if err := s.Probe(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newSSHClientConfig 根据配置创建SSH认证和服务器公钥验证方式
+func newSSHClientConfig(config *SFTPConfig) (*ssh.ClientConfig, error) {
+	var auths []ssh.AuthMethod
+	if config.PrivateKey != "" {
+		keyData, err := os.ReadFile(config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("读取SFTP私钥失败: %w", err)
+		}
+		var signer ssh.Signer
+		if config.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(config.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(keyData)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析SFTP私钥失败: %w", err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if config.Password != "" {
+		auths = append(auths, ssh.Password(config.Password))
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	if config.HostKeyFingerprint != "" {
+		fingerprint := config.HostKeyFingerprint
+		hostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if actual := ssh.FingerprintSHA256(key); actual != fingerprint {
+				return fmt.Errorf("服务器公钥指纹不匹配: %s", actual)
+			}
+			return nil
+		}
+	} else {
+		callback, err := knownhosts.New(config.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("读取known_hosts文件失败: %w", err)
+		}
+		hostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			err := callback(hostname, remote, key)
+			var keyErr *knownhosts.KeyError
+			if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+				return fmt.Errorf("known_hosts中没有服务器 %s 的公钥（指纹 %s），请先使用 ssh-keyscan 添加: %w",
+					hostname, ssh.FingerprintSHA256(key), err)
+			}
+			return err
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            config.Username,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         config.Timeout,
+	}, nil
+}
+
+// SetProxyURLFunc 设置生成代理下载链接的函数
+func (s *SFTPService) SetProxyURLFunc(fn storage.ProxyURLFunc) {
+	s.proxyURL = fn
+}
+
+// session 返回当前的SFTP连接，并在上下文结束时关闭该连接，避免服务器无响应时操作一直阻塞
+// 调用方在操作完成后需要调用返回的release停止监视；连接被关闭后，共用连接的其他操作会收到可重试的连接中断错误
+func (s *SFTPService) session(ctx context.Context) (client *sftpclient.Client, release func(), err error) {
+	client, sshClient, err := s.connect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ctx.Done() == nil {
+		return client, func() {}, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			sshClient.Close()
+		case <-done:
+		}
+	}()
+	return client, func() { close(done) }, nil
+}
+
+// connect 返回当前的SFTP连接和底层的SSH连接，没有可用连接时重新建立
+func (s *SFTPService) connect(ctx context.Context) (*sftpclient.Client, *ssh.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		return s.client, s.sshClient, nil
+	}
+
+	addr := s.config.Addr()
+	dialer := net.Dialer{Timeout: s.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("连接SFTP服务器失败: %w", err)
+	}
+
+	// SSH握手和SFTP初始化没有单独的超时，使用连接的截止时间限制，上下文的截止时间更早时以上下文为准
+	deadline := time.Now().Add(s.config.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, s.sshConfig)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("SSH连接失败: %w", err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	client, err := sftpclient.NewClient(sshClient, sftpclient.UseConcurrentWrites(true))
+	if err != nil {
+		sshClient.Close()
+		return nil, nil, fmt.Errorf("启动SFTP会话失败: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	// 连接断开后清除当前连接，下一次操作会重新连接
+	done := make(chan struct{})
+	go func() {
+		sshClient.Wait()
+		close(done)
+		client.Close()
+		s.mu.Lock()
+		if s.client == client {
+			s.client, s.sshClient = nil, nil
+		}
+		s.mu.Unlock()
+	}()
+	go keepalive(sshClient, done)
+
+	s.client, s.sshClient = client, sshClient
+	return client, sshClient, nil
+}
+
+// keepalive 定期发送心跳，及时发现被防火墙或服务器断开的空闲连接
+func keepalive(sshClient *ssh.Client, done <-chan struct{}) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if _, _, err := sshClient.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				sshClient.Close()
+				return
+			}
+		}
+	}
+}
+
+// wrapError 将连接中断转换为可重试的错误，其他错误原样返回
+func wrapError(err error) error {
+	if errors.Is(err, sftpclient.ErrSSHFxConnectionLost) {
+		return fmt.Errorf("SFTP连接中断: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// isNotExist 返回错误是否表示文件不存在
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// remotePath 返回对象在服务器上的路径，对象名包含 .. 等超出存放目录的路径时返回错误
+func (s *SFTPService) remotePath(objectName string) (string, error) {
+	p := path.Join(s.root, strings.TrimPrefix(objectName, "/"))
+	if p != s.root && !strings.HasPrefix(p, strings.TrimSuffix(s.root, "/")+"/") {
+		return "", &fs.PathError{Op: "resolve", Path: objectName, Err: fs.ErrInvalid}
+	}
+	return p, nil
+}
+
+// fileInfoToObjectInfo 将文件信息转换为对象元数据，SFTP没有ETag
+func fileInfoToObjectInfo(key string, info os.FileInfo) storage.ObjectInfo {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return storage.ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  contentType,
+		LastModified: info.ModTime(),
+	}
+}
+
+// UploadFile 上传文件到SFTP服务器
+// 先写入同一目录下的临时文件，完成后重命名为目标文件，其他程序不会读到写了一半的文件
+func (s *SFTPService) UploadFile(ctx context.Context, objectName string, localFile string, progressFn storage.ProgressCallback) (interface{}, error) {
+	// 检查文件是否存在和可访问
+	fileInfo, err := os.Stat(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("文件访问错误: %w", err)
+	}
+
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	target, err := s.remotePath(objectName)
+	if err != nil {
+		return nil, fmt.Errorf("对象名称无效: %w", err)
+	}
+	// 上传可能持续很久，取消时由进度读取器停止写入，不关闭其他操作共用的连接
+	client, _, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Dir(target)
+	if err := client.MkdirAll(dir); err != nil {
+		return nil, fmt.Errorf("创建目录 %s 失败: %w", dir, wrapError(err))
+	}
+
+	file, err := os.Open(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开文件: %w", err)
+	}
+	defer file.Close()
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	tempPath := path.Join(dir, "."+path.Base(target)+"."+hex.EncodeToString(suffix)+tempFileSuffix)
+	if err := s.writeFile(ctx, client, tempPath, file, fileInfo.Size(), progressFn); err != nil {
+		client.Remove(tempPath)
+		return nil, fmt.Errorf("上传文件失败: %w", wrapError(err))
+	}
+
+	if err := rename(client, tempPath, target); err != nil {
+		client.Remove(tempPath)
+		return nil, fmt.Errorf("重命名临时文件失败: %w", wrapError(err))
+	}
+
+	return &storage.ObjectInfo{
+		Key:          objectName,
+		Size:         fileInfo.Size(),
+		ContentType:  fileInfoToObjectInfo(objectName, fileInfo).ContentType,
+		LastModified: time.Now(),
+	}, nil
+}
+
+// writeFile 将本地文件内容写入服务器上的文件，上下文中指定了带宽限速器时按限速读取文件
+func (s *SFTPService) writeFile(ctx context.Context, client *sftpclient.Client, remotePath string, file *os.File, size int64, progressFn storage.ProgressCallback) error {
+	remote, err := client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+
+	reader := storage.NewProgressReader(ctx, storage.NewThrottledReader(ctx, file, storage.RateLimiterFrom(ctx)), size, progressFn)
+	if _, err := remote.ReadFrom(reader); err != nil {
+		remote.Close()
+		return err
+	}
+	return remote.Close()
+}
+
+// rename 将临时文件重命名为目标文件，覆盖已存在的文件
+// 优先使用OpenSSH的posix-rename扩展原子替换，服务器不支持时先删除目标文件
+func rename(client *sftpclient.Client, from, to string) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		return client.PosixRename(from, to)
+	}
+	if err := client.Remove(to); err != nil && !isNotExist(err) {
+		return err
+	}
+	return client.Rename(from, to)
+}
+
+// IsObjectExist 检查对象是否存在于SFTP服务器
+func (s *SFTPService) IsObjectExist(ctx context.Context, objectName string) (bool, error) {
+	_, err := s.StatObject(ctx, objectName)
+	if errors.Is(err, storage.ErrObjectNotExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("检查对象是否存在失败: %w", err)
+	}
+	return true, nil
+}
+
+// StatObject 获取文件的元数据，目录视为对象不存在
+func (s *SFTPService) StatObject(ctx context.Context, objectName string) (*storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	remotePath, err := s.remotePath(objectName)
+	if err != nil {
+		return nil, storage.ErrObjectNotExists
+	}
+	client, release, err := s.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	info, err := client.Stat(remotePath)
+	if isNotExist(err) {
+		return nil, storage.ErrObjectNotExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取对象元数据失败: %w", wrapError(err))
+	}
+	if !info.Mode().IsRegular() {
+		return nil, storage.ErrObjectNotExists
+	}
+
+	objectInfo := fileInfoToObjectInfo(objectName, info)
+	return &objectInfo, nil
+}
+
+// ListObjects 列出指定前缀下的所有文件，跳过上传中的临时文件
+func (s *SFTPService) ListObjects(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	// 确保前缀没有前导斜杠
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	// 从前缀所在的目录开始遍历
+	dir := s.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		var err error
+		if dir, err = s.remotePath(prefix[:i]); err != nil {
+			return nil, nil
+		}
+	}
+
+	client, release, err := s.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var objects []storage.ObjectInfo
+	walker := client.Walk(dir)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := walker.Err(); err != nil {
+			if walker.Path() == dir && isNotExist(err) {
+				return nil, nil
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("列出对象失败: %w", wrapError(err))
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), s.root), "/")
+		info := walker.Stat()
+		if info.IsDir() {
+			// 只进入可能包含匹配对象的目录
+			if key != "" && walker.Path() != dir &&
+				!strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				walker.SkipDir()
+			}
+			continue
+		}
+		if !info.Mode().IsRegular() || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if name := path.Base(key); strings.HasPrefix(name, ".") && strings.HasSuffix(name, tempFileSuffix) {
+			continue
+		}
+		objects = append(objects, fileInfoToObjectInfo(key, info))
+	}
+
+	return objects, nil
+}
+
+// GetObject 读取SFTP服务器上的文件内容
+func (s *SFTPService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	remotePath, err := s.remotePath(objectName)
+	if err != nil {
+		return nil, nil, storage.ErrObjectNotExists
+	}
+	// 只在打开文件期间监视上下文，读取内容时由调用方关闭文件结束下载，不关闭其他操作共用的连接
+	client, release, err := s.session(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	file, err := client.Open(remotePath)
+	if isNotExist(err) {
+		return nil, nil, storage.ErrObjectNotExists
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取对象失败: %w", wrapError(err))
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("读取对象失败: %w", wrapError(err))
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, storage.ErrObjectNotExists
+	}
+
+	objectInfo := fileInfoToObjectInfo(objectName, info)
+	return file, &objectInfo, nil
+}
+
+// GeneratePresignedURL SFTP不支持预签名上传
+func (s *SFTPService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return "", nil, storage.ErrPresignNotSupported
+}
+
+// GeneratePresignedDownloadURL 生成由本服务代理下载的链接
+func (s *SFTPService) GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	if s.proxyURL == nil {
+		return "", nil, storage.ErrPresignNotSupported
+	}
+	proxyURL, err := s.proxyURL(strings.TrimPrefix(objectName, "/"), expiration)
+	if err != nil {
+		return "", nil, fmt.Errorf("生成代理下载链接失败: %w", err)
+	}
+	return proxyURL, make(map[string]string), nil
+}
+
+// GetBucketDomain 返回 sftp://主机/目录 形式的地址
+func (s *SFTPService) GetBucketDomain() string {
+	return "sftp://" + s.config.Addr() + s.root
+}
+
+// Probe 检查与服务器的连接、认证和存放文件的目录是否可用
+func (s *SFTPService) Probe(ctx context.Context) error {
+	client, release, err := s.session(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	info, err := client.Stat(s.root)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("访问SFTP目录 %s 超时: %w", s.root, ctxErr)
+		}
+		return fmt.Errorf("访问SFTP目录 %s 失败: %w", s.root, wrapError(err))
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("SFTP地址不是目录: %s", s.root)
+	}
+	return nil
+}
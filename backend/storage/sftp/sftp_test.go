@@ -0,0 +1,273 @@
+package sftp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	sftpserver "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"go-uploader/storage"
+)
+
+// startTestServer 启动进程内的SSH服务器，提供SFTP子系统，返回监听地址和服务器公钥指纹
+func startTestServer(t *testing.T) (addr, fingerprint string) {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "lab" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("认证失败")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveConn(conn, config)
+		}
+	}()
+	return listener.Addr().String(), ssh.FingerprintSHA256(signer.PublicKey())
+}
+
+// serveConn 处理一个SSH连接，只接受sftp子系统
+func serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer channel.Close()
+			for req := range channelRequests {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server, err := sftpserver.NewServer(channel)
+					if err == nil {
+						server.Serve()
+					}
+					return
+				}
+			}
+		}()
+	}
+}
+
+// newTestService 创建连接进程内SSH服务器的存储服务，文件存放在临时目录中
+func newTestService(t *testing.T) (*SFTPService, string) {
+	t.Helper()
+
+	addr, fingerprint := startTestServer(t)
+	root := t.TempDir()
+	s, err := NewSFTPService(&SFTPConfig{
+		Host:               addr,
+		Port:               22,
+		Username:           "lab",
+		Password:           "secret",
+		HostKeyFingerprint: fingerprint,
+		Root:               root,
+		Timeout:            5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("创建SFTP存储服务失败: %v", err)
+	}
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.client != nil {
+			s.client.Close()
+			s.sshClient.Close()
+		}
+	})
+	return s, root
+}
+
+// writeTempFile 在临时目录中创建内容为data的文件
+func writeTempFile(t *testing.T, name, data string) string {
+	t.Helper()
+	localFile := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(localFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return localFile
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, root := newTestService(t)
+	ctx := context.Background()
+
+	files := map[string]string{
+		"docs/report 2024.txt": "quarterly report",
+		"docs/sub/notes.txt":   "nested notes",
+		"docs2/other.txt":      "not under docs/",
+	}
+	for objectName, data := range files {
+		var transferred int64
+		progressFn := func(increment, current, total int64) { transferred = current }
+		if _, err := s.UploadFile(ctx, objectName, writeTempFile(t, filepath.Base(objectName), data), progressFn); err != nil {
+			t.Fatalf("上传 %s 失败: %v", objectName, err)
+		}
+		if transferred != int64(len(data)) {
+			t.Errorf("上传 %s 的进度为 %d，期望 %d", objectName, transferred, len(data))
+		}
+	}
+
+	// 上传中断留下的临时文件不应被列出
+	if err := os.WriteFile(filepath.Join(root, "docs", ".partial.txt.0123abcd"+tempFileSuffix), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := s.StatObject(ctx, "docs/report 2024.txt")
+	if err != nil {
+		t.Fatalf("获取元数据失败: %v", err)
+	}
+	if info.Key != "docs/report 2024.txt" || info.Size != int64(len("quarterly report")) {
+		t.Errorf("元数据不正确: %+v", info)
+	}
+
+	if _, err := s.StatObject(ctx, "docs/missing.txt"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("不存在的对象返回 %v，期望 ErrObjectNotExists", err)
+	}
+	if _, err := s.StatObject(ctx, "docs"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("目录返回 %v，期望 ErrObjectNotExists", err)
+	}
+	if exists, err := s.IsObjectExist(ctx, "docs/sub/notes.txt"); err != nil || !exists {
+		t.Errorf("IsObjectExist = %v, %v，期望存在", exists, err)
+	}
+
+	objects, err := s.ListObjects(ctx, "docs/")
+	if err != nil {
+		t.Fatalf("列出对象失败: %v", err)
+	}
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "docs/report 2024.txt" || keys[1] != "docs/sub/notes.txt" {
+		t.Errorf("列出的对象为 %v", keys)
+	}
+
+	reader, info, err := s.GetObject(ctx, "docs/sub/notes.txt")
+	if err != nil {
+		t.Fatalf("读取对象失败: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "nested notes" {
+		t.Errorf("读取的内容为 %q, %v", data, err)
+	}
+	if info.Size != int64(len("nested notes")) {
+		t.Errorf("读取对象返回的大小为 %d", info.Size)
+	}
+	if _, _, err := s.GetObject(ctx, "docs/missing.txt"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("读取不存在的对象返回 %v，期望 ErrObjectNotExists", err)
+	}
+
+	if _, err := s.StatObject(ctx, "../outside.txt"); err == nil {
+		t.Errorf("目录之外的对象名没有返回错误")
+	}
+}
+
+func TestPresign(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	if _, _, err := s.GeneratePresignedURL(ctx, "a.txt", time.Hour); !errors.Is(err, storage.ErrPresignNotSupported) {
+		t.Errorf("预签名上传返回 %v，期望 ErrPresignNotSupported", err)
+	}
+	if _, _, err := s.GeneratePresignedDownloadURL(ctx, "a.txt", time.Hour); !errors.Is(err, storage.ErrPresignNotSupported) {
+		t.Errorf("未设置代理链接时预签名下载返回 %v，期望 ErrPresignNotSupported", err)
+	}
+
+	s.SetProxyURLFunc(func(objectName string, expiration time.Duration) (string, error) {
+		return "https://upload.example.com/api/proxy/" + objectName, nil
+	})
+	proxyURL, _, err := s.GeneratePresignedDownloadURL(ctx, "/docs/a.txt", time.Hour)
+	if err != nil {
+		t.Fatalf("生成代理下载链接失败: %v", err)
+	}
+	if proxyURL != "https://upload.example.com/api/proxy/docs/a.txt" {
+		t.Errorf("代理下载链接为 %s", proxyURL)
+	}
+}
+
+// TestProbeDeadline 服务器不响应时，Probe应在上下文的截止时间返回，而不是等待连接超时
+func TestProbeDeadline(t *testing.T) {
+	s, _ := newTestService(t)
+
+	// 断开已有连接，改为连接一个只接受TCP连接、从不响应的地址
+	s.mu.Lock()
+	s.client.Close()
+	s.sshClient.Close()
+	s.client, s.sshClient = nil, nil
+	s.mu.Unlock()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	s.config.Host = listener.Addr().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := s.Probe(ctx); err == nil {
+		t.Fatal("服务器不响应时Probe没有返回错误")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Probe在 %v 后才返回", elapsed)
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -14,6 +15,15 @@ type StorageConfig interface {
 	Validate() error
 }
 
+// ObjectInfo 存储对象的元数据
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"contentType"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"lastModified"`
+}
+
 // ProgressCallback 上传进度回调函数
 type ProgressCallback func(increment, transferred, total int64)
 
@@ -25,14 +35,33 @@ type StorageService interface {
 	// IsObjectExist 检查对象是否存在
 	IsObjectExist(ctx context.Context, objectName string) (bool, error)
 
+	// StatObject 获取对象元数据，对象不存在时返回 ErrObjectNotExists
+	StatObject(ctx context.Context, objectName string) (*ObjectInfo, error)
+
+	// ListObjects 列出指定前缀下的所有对象（递归）
+	ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error)
+
+	// GetObject 读取对象内容，调用方负责关闭返回的读取器
+	GetObject(ctx context.Context, objectName string) (io.ReadCloser, *ObjectInfo, error)
+
 	// GeneratePresignedURL 生成预签名上传URL
 	GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error)
 
 	// GeneratePresignedDownloadURL 生成预签名下载URL
 	GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error)
 
-	// GetBucketDomain 获取存储桶的域名
+	// GetBucketDomain 获取存储桶的域名，文件地址为 域名/对象名
+	// 可以带协议（例如 http://minio:9000/uploads），不带协议时按https访问
 	GetBucketDomain() string
+
+	// Probe 检查存储服务是否可用（网络可达、凭证有效、存储桶可访问），用于就绪检查
+	Probe(ctx context.Context) error
+}
+
+// StaleUploadAborter 可选接口，由支持断点续传的存储服务实现，用于清理长时间未完成的分片上传
+type StaleUploadAborter interface {
+	// AbortStaleUploads 中止超过指定时长仍未完成的分片上传，返回中止的数量
+	AbortStaleUploads(ctx context.Context, maxAge time.Duration) (int, error)
 }
 
 // StorageServiceFactory 存储服务工厂接口
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// throttleChunkSize 限速读取器单次读取的最大字节数，令牌桶的容量不能小于该值
+const throttleChunkSize = 32 * 1024
+
+// RateLimiter 带宽限速器，在读取n个字节后等待令牌
+// golang.org/x/time/rate.Limiter 满足该接口
+type RateLimiter interface {
+	WaitN(ctx context.Context, n int) error
+}
+
+// rateLimiterKey 上下文中带宽限速器的键
+type rateLimiterKey struct{}
+
+// WithRateLimiter 在上下文中指定本次上传使用的带宽限速器
+func WithRateLimiter(ctx context.Context, limiter RateLimiter) context.Context {
+	if limiter == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rateLimiterKey{}, limiter)
+}
+
+// RateLimiterFrom 获取上下文中的带宽限速器，未指定时返回nil
+func RateLimiterFrom(ctx context.Context) RateLimiter {
+	limiter, _ := ctx.Value(rateLimiterKey{}).(RateLimiter)
+	return limiter
+}
+
+// throttledReader 按限速器的速率读取数据
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter RateLimiter
+}
+
+// throttledReadSeeker 支持Seek的限速读取器，便于SDK在重试时重新读取请求体
+type throttledReadSeeker struct {
+	*throttledReader
+	seeker io.Seeker
+}
+
+// NewThrottledReader 创建限速读取器，limiter为nil时直接返回原读取器
+// 原读取器支持Seek时返回的读取器同样支持Seek
+func NewThrottledReader(ctx context.Context, r io.Reader, limiter RateLimiter) io.Reader {
+	if limiter == nil {
+		return r
+	}
+
+	reader := &throttledReader{ctx: ctx, reader: r, limiter: limiter}
+	if seeker, ok := r.(io.Seeker); ok {
+		return &throttledReadSeeker{throttledReader: reader, seeker: seeker}
+	}
+	return reader
+}
+
+// Read 读取数据，并按读取的字节数等待令牌
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunkSize {
+		p = p[:throttleChunkSize]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Seek 移动读取位置
+func (r *throttledReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
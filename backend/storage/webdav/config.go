@@ -0,0 +1,74 @@
+package webdav
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"go-uploader/storage"
+)
+
+// WebDAVConfig WebDAV存储配置
+type WebDAVConfig struct {
+	URL      string        // 存放文件的目录地址，例如 https://cloud.example.com/remote.php/dav/files/user/uploads
+	Username string        // 基本认证的用户名，为空表示不认证
+	Password string        // 基本认证的密码，Nextcloud建议使用应用密码
+	Timeout  time.Duration // 除上传和下载外的请求超时时间
+}
+
+// GetType 返回存储类型标识
+func (c *WebDAVConfig) GetType() string {
+	return storage.TypeWebDAV
+}
+
+// Validate 验证配置的合法性
+func (c *WebDAVConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("缺少必要的WebDAV配置")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("WebDAV地址必须是完整的http或https地址: %s", c.URL)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("WebDAV请求超时时间必须大于0")
+	}
+	return nil
+}
+
+// LoadWebDAVConfigFromEnv 从环境变量加载WebDAV配置
+func LoadWebDAVConfigFromEnv() (*WebDAVConfig, error) {
+	return LoadWebDAVConfigFromEnvPrefix("WEBDAV")
+}
+
+// LoadWebDAVConfigFromEnvPrefix 从带指定前缀的环境变量加载WebDAV配置，例如前缀 WEBDAV 对应 WEBDAV_URL
+func LoadWebDAVConfigFromEnvPrefix(prefix string) (*WebDAVConfig, error) {
+	// 尝试加载.env文件，但不强制要求
+	_ = godotenv.Load()
+
+	config := &WebDAVConfig{
+		URL:      strings.TrimSuffix(os.Getenv(prefix+"_URL"), "/"),
+		Username: os.Getenv(prefix + "_USERNAME"),
+		Password: os.Getenv(prefix + "_PASSWORD"),
+		Timeout:  30 * time.Second,
+	}
+	if value := os.Getenv(prefix + "_TIMEOUT_SECONDS"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("WebDAV配置验证失败: %s_TIMEOUT_SECONDS 必须是正整数: %s", prefix, value)
+		}
+		config.Timeout = time.Duration(seconds) * time.Second
+	}
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("WebDAV配置验证失败: %w", err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,29 @@
+package webdav
+
+import (
+	"go-uploader/storage"
+)
+
+// 在init函数中注册WebDAV存储服务工厂
+func init() {
+	// 创建工厂函数
+	factory := func(config storage.StorageConfig) (storage.StorageService, error) {
+		webdavConfig, ok := config.(*WebDAVConfig)
+		if !ok {
+			return nil, storage.ErrInvalidConfig
+		}
+		return NewWebDAVService(webdavConfig)
+	}
+
+	// 注册到全局工厂
+	storage.RegisterStorageFactory(storage.TypeWebDAV, factory)
+
+	// 注册配置加载函数，用于按名称配置多个存储后端
+	storage.RegisterConfigLoader(storage.TypeWebDAV, "WEBDAV", func(prefix string) (storage.StorageConfig, error) {
+		config, err := LoadWebDAVConfigFromEnvPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return config, nil
+	})
+}
@@ -0,0 +1,469 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-uploader/storage"
+)
+
+// propfindBody PROPFIND请求体，只查询需要的属性
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getcontenttype/>
+    <d:getetag/>
+    <d:getlastmodified/>
+  </d:prop>
+</d:propfind>`
+
+// StatusError WebDAV服务器返回的错误状态，状态码用于判断是否可以重试
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.Path, e.Status)
+}
+
+// HttpStatusCode 返回HTTP状态码
+func (e *StatusError) HttpStatusCode() int {
+	return e.StatusCode
+}
+
+// multistatus PROPFIND的响应
+type multistatus struct {
+	Responses []davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"href"`
+	Propstats []davPropstat `xml:"propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"prop"`
+	Status string  `xml:"status"`
+}
+
+type davProp struct {
+	ResourceType struct {
+		Collection *struct{} `xml:"collection"`
+	} `xml:"resourcetype"`
+	ContentLength string `xml:"getcontentlength"`
+	ContentType   string `xml:"getcontenttype"`
+	ETag          string `xml:"getetag"`
+	LastModified  string `xml:"getlastmodified"`
+}
+
+// davEntry PROPFIND返回的一个文件或目录
+type davEntry struct {
+	key          string
+	isCollection bool
+	info         storage.ObjectInfo
+}
+
+// WebDAVService WebDAV存储服务实现
+// WebDAV没有预签名URL，下载链接由本服务代理（见 storage.ProxyDownloader），不支持预签名直传
+type WebDAVService struct {
+	client   *http.Client
+	config   *WebDAVConfig
+	baseURL  *url.URL
+	basePath string // 目录地址的路径部分，用于把PROPFIND返回的地址转换为对象名
+	proxyURL storage.ProxyURLFunc
+
+	// 已经确认存在的目录，避免每次上传都发送MKCOL
+	collections sync.Map
+}
+
+// NewWebDAVService 创建新的WebDAV存储服务
+func NewWebDAVService(config *WebDAVConfig) (*WebDAVService, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	baseURL, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("解析WebDAV地址失败: %w", err)
+	}
+
+	s := &WebDAVService{
+		client:   &http.Client{},
+		config:   config,
+		baseURL:  baseURL,
+		basePath: strings.TrimSuffix(baseURL.Path, "/"),
+	}
+
+	// 检查目录是否存在且可以访问
+	if err := s.Probe(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetProxyURLFunc 设置生成代理下载链接的函数
+func (s *WebDAVService) SetProxyURLFunc(fn storage.ProxyURLFunc) {
+	s.proxyURL = fn
+}
+
+// objectURL 返回对象的完整地址，对象名的每一段分别转义，以 / 结尾的名称表示目录
+func (s *WebDAVService) objectURL(objectName string) string {
+	isCollection := strings.HasSuffix(objectName, "/")
+	objectName = strings.Trim(objectName, "/")
+	if objectName == "" {
+		return s.config.URL + "/"
+	}
+	segments := strings.Split(objectName, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	if isCollection {
+		return s.config.URL + "/" + strings.Join(segments, "/") + "/"
+	}
+	return s.config.URL + "/" + strings.Join(segments, "/")
+}
+
+// do 发送请求，返回2xx以外的状态码时关闭响应并返回 StatusError
+func (s *WebDAVService) do(ctx context.Context, method, objectName string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(objectName), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		return nil, &StatusError{Method: method, Path: "/" + objectName, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return resp, nil
+}
+
+// statusCode 返回错误中WebDAV服务器的状态码，不是 StatusError 时返回0
+func statusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
+// isNotFound 返回错误是否表示文件或目录不存在
+func isNotFound(err error) bool {
+	code := statusCode(err)
+	return code == http.StatusNotFound || code == http.StatusGone
+}
+
+// propfind 查询文件或目录的属性，depth 为 "0" 时只查询自身，为 "1" 时同时查询目录下的直接子项
+func (s *WebDAVService) propfind(ctx context.Context, objectName string, depth string) ([]davEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	resp, err := s.do(ctx, "PROPFIND", objectName, strings.NewReader(propfindBody), http.Header{
+		"Depth":        {depth},
+		"Content-Type": {"application/xml; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析PROPFIND响应失败: %w", err)
+	}
+
+	entries := make([]davEntry, 0, len(result.Responses))
+	for _, response := range result.Responses {
+		key, ok := s.hrefToKey(response.Href)
+		if !ok {
+			continue
+		}
+		for _, propstat := range response.Propstats {
+			// 只使用成功返回的属性，服务器不支持的属性在404的propstat中
+			if !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			entries = append(entries, davEntry{
+				key:          key,
+				isCollection: propstat.Prop.ResourceType.Collection != nil,
+				info:         propToObjectInfo(key, propstat.Prop),
+			})
+			break
+		}
+	}
+	return entries, nil
+}
+
+// hrefToKey 将PROPFIND返回的地址（绝对路径或完整URL）转换为对象名
+func (s *WebDAVService) hrefToKey(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	if p == s.basePath {
+		return "", true
+	}
+	if !strings.HasPrefix(p, s.basePath+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(p, s.basePath+"/"), true
+}
+
+// propToObjectInfo 将PROPFIND返回的属性转换为对象元数据
+func propToObjectInfo(key string, prop davProp) storage.ObjectInfo {
+	size, _ := strconv.ParseInt(strings.TrimSpace(prop.ContentLength), 10, 64)
+	modified, _ := http.ParseTime(strings.TrimSpace(prop.LastModified))
+	return storage.ObjectInfo{
+		Key:          key,
+		Size:         size,
+		ContentType:  prop.ContentType,
+		ETag:         strings.Trim(prop.ETag, "\""),
+		LastModified: modified,
+	}
+}
+
+// ensureCollections 逐级创建对象所在的目录，已存在的目录会被跳过
+func (s *WebDAVService) ensureCollections(ctx context.Context, objectName string) error {
+	segments := strings.Split(objectName, "/")
+	for i := 1; i < len(segments); i++ {
+		dir := strings.Join(segments[:i], "/")
+		if _, ok := s.collections.Load(dir); ok {
+			continue
+		}
+
+		mkcolCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+		resp, err := s.do(mkcolCtx, "MKCOL", dir+"/", nil, nil)
+		cancel()
+		if err == nil {
+			resp.Body.Close()
+		} else if statusCode(err) != http.StatusMethodNotAllowed {
+			// 目录已存在时服务器返回405，其他错误说明无法创建
+			return fmt.Errorf("创建目录 %s 失败: %w", dir, err)
+		}
+		s.collections.Store(dir, true)
+	}
+	return nil
+}
+
+// UploadFile 上传文件到WebDAV服务器，对象名中的目录会逐级创建
+func (s *WebDAVService) UploadFile(ctx context.Context, objectName string, localFile string, progressFn storage.ProgressCallback) (interface{}, error) {
+	// 检查文件是否存在和可访问
+	fileInfo, err := os.Stat(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("文件访问错误: %w", err)
+	}
+
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+	if err := s.ensureCollections(ctx, objectName); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(localFile)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开文件: %w", err)
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(localFile))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// 创建进度读取器，上下文中指定了带宽限速器时按限速读取文件
+	reader := storage.NewProgressReader(ctx, storage.NewThrottledReader(ctx, file, storage.RateLimiterFrom(ctx)), fileInfo.Size(), progressFn)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(objectName), reader)
+	if err != nil {
+		return nil, fmt.Errorf("上传文件失败: %w", err)
+	}
+	req.ContentLength = fileInfo.Size()
+	req.Header.Set("Content-Type", contentType)
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("上传文件失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("上传文件失败: %w",
+			&StatusError{Method: http.MethodPut, Path: "/" + objectName, StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+
+	return &storage.ObjectInfo{
+		Key:          objectName,
+		Size:         fileInfo.Size(),
+		ContentType:  contentType,
+		ETag:         strings.Trim(resp.Header.Get("ETag"), "\""),
+		LastModified: time.Now(),
+	}, nil
+}
+
+// IsObjectExist 检查对象是否存在于WebDAV服务器
+func (s *WebDAVService) IsObjectExist(ctx context.Context, objectName string) (bool, error) {
+	_, err := s.StatObject(ctx, objectName)
+	if errors.Is(err, storage.ErrObjectNotExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("检查对象是否存在失败: %w", err)
+	}
+	return true, nil
+}
+
+// StatObject 通过PROPFIND获取文件的元数据，目录视为对象不存在
+func (s *WebDAVService) StatObject(ctx context.Context, objectName string) (*storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	entries, err := s.propfind(ctx, objectName, "0")
+	if isNotFound(err) {
+		return nil, storage.ErrObjectNotExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取对象元数据失败: %w", err)
+	}
+	if len(entries) == 0 || entries[0].isCollection {
+		return nil, storage.ErrObjectNotExists
+	}
+
+	info := entries[0].info
+	info.Key = objectName
+	return &info, nil
+}
+
+// ListObjects 列出指定前缀下的所有文件
+// 很多服务器禁用了 Depth: infinity，因此从前缀所在的目录开始逐级查询子目录
+func (s *WebDAVService) ListObjects(ctx context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	// 确保前缀没有前导斜杠
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i]
+	}
+
+	var objects []storage.ObjectInfo
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		entries, err := s.propfind(ctx, dir+"/", "1")
+		if isNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("列出对象失败: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.key == dir {
+				continue
+			}
+			if entry.isCollection {
+				// 只进入可能包含匹配对象的目录
+				if strings.HasPrefix(entry.key+"/", prefix) || strings.HasPrefix(prefix, entry.key+"/") {
+					if err := walk(entry.key); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if strings.HasPrefix(entry.key, prefix) {
+				objects = append(objects, entry.info)
+			}
+		}
+		return nil
+	}
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// GetObject 读取WebDAV服务器上的文件内容
+func (s *WebDAVService) GetObject(ctx context.Context, objectName string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	// 确保对象名称没有前导斜杠
+	objectName = strings.TrimPrefix(objectName, "/")
+
+	resp, err := s.do(ctx, http.MethodGet, objectName, nil, nil)
+	if isNotFound(err) {
+		return nil, nil, storage.ErrObjectNotExists
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取对象失败: %w", err)
+	}
+
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return resp.Body, &storage.ObjectInfo{
+		Key:          objectName,
+		Size:         resp.ContentLength,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         strings.Trim(resp.Header.Get("ETag"), "\""),
+		LastModified: modified,
+	}, nil
+}
+
+// GeneratePresignedURL WebDAV不支持预签名上传
+func (s *WebDAVService) GeneratePresignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	return "", nil, storage.ErrPresignNotSupported
+}
+
+// GeneratePresignedDownloadURL 生成由本服务代理下载的链接
+func (s *WebDAVService) GeneratePresignedDownloadURL(ctx context.Context, objectName string, expiration time.Duration) (string, map[string]string, error) {
+	if s.proxyURL == nil {
+		return "", nil, storage.ErrPresignNotSupported
+	}
+	proxyURL, err := s.proxyURL(strings.TrimPrefix(objectName, "/"), expiration)
+	if err != nil {
+		return "", nil, fmt.Errorf("生成代理下载链接失败: %w", err)
+	}
+	return proxyURL, make(map[string]string), nil
+}
+
+// GetBucketDomain 返回存放文件的目录地址
+func (s *WebDAVService) GetBucketDomain() string {
+	return s.config.URL
+}
+
+// Probe 查询存放文件的目录，检查网络、认证和目录是否可用
+func (s *WebDAVService) Probe(ctx context.Context) error {
+	entries, err := s.propfind(ctx, "", "0")
+	if err != nil {
+		return fmt.Errorf("访问WebDAV目录失败: %w", err)
+	}
+	if len(entries) == 0 || !entries[0].isCollection {
+		return fmt.Errorf("WebDAV地址不是目录: %s", path.Clean(s.baseURL.Path))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	"go-uploader/storage"
+)
+
+// newTestService 启动进程内的WebDAV服务器，返回指向其中 uploads 目录的存储服务
+func newTestService(t *testing.T) *WebDAVService {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "uploads"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.Dir(root),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "lab" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	s, err := NewWebDAVService(&WebDAVConfig{
+		URL:      server.URL + "/dav/uploads",
+		Username: "lab",
+		Password: "secret",
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("创建WebDAV存储服务失败: %v", err)
+	}
+	return s
+}
+
+// writeTempFile 在临时目录中创建内容为data的文件
+func writeTempFile(t *testing.T, name, data string) string {
+	t.Helper()
+	localFile := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(localFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return localFile
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	files := map[string]string{
+		"docs/report 2024.txt": "quarterly report",
+		"docs/sub/notes.txt":   "nested notes",
+		"docs2/other.txt":      "not under docs/",
+	}
+	for objectName, data := range files {
+		var transferred int64
+		progressFn := func(increment, current, total int64) { transferred = current }
+		if _, err := s.UploadFile(ctx, objectName, writeTempFile(t, filepath.Base(objectName), data), progressFn); err != nil {
+			t.Fatalf("上传 %s 失败: %v", objectName, err)
+		}
+		if transferred != int64(len(data)) {
+			t.Errorf("上传 %s 的进度为 %d，期望 %d", objectName, transferred, len(data))
+		}
+	}
+
+	info, err := s.StatObject(ctx, "docs/report 2024.txt")
+	if err != nil {
+		t.Fatalf("获取元数据失败: %v", err)
+	}
+	if info.Key != "docs/report 2024.txt" || info.Size != int64(len("quarterly report")) {
+		t.Errorf("元数据不正确: %+v", info)
+	}
+
+	if _, err := s.StatObject(ctx, "docs/missing.txt"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("不存在的对象返回 %v，期望 ErrObjectNotExists", err)
+	}
+	if _, err := s.StatObject(ctx, "docs"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("目录返回 %v，期望 ErrObjectNotExists", err)
+	}
+	if exists, err := s.IsObjectExist(ctx, "docs/sub/notes.txt"); err != nil || !exists {
+		t.Errorf("IsObjectExist = %v, %v，期望存在", exists, err)
+	}
+
+	objects, err := s.ListObjects(ctx, "docs/")
+	if err != nil {
+		t.Fatalf("列出对象失败: %v", err)
+	}
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "docs/report 2024.txt" || keys[1] != "docs/sub/notes.txt" {
+		t.Errorf("列出的对象为 %v", keys)
+	}
+
+	reader, info, err := s.GetObject(ctx, "docs/sub/notes.txt")
+	if err != nil {
+		t.Fatalf("读取对象失败: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "nested notes" {
+		t.Errorf("读取的内容为 %q, %v", data, err)
+	}
+	if info.Size != int64(len("nested notes")) {
+		t.Errorf("读取对象返回的大小为 %d", info.Size)
+	}
+	if _, _, err := s.GetObject(ctx, "docs/missing.txt"); !errors.Is(err, storage.ErrObjectNotExists) {
+		t.Errorf("读取不存在的对象返回 %v，期望 ErrObjectNotExists", err)
+	}
+}
+
+func TestPresign(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	if _, _, err := s.GeneratePresignedURL(ctx, "a.txt", time.Hour); !errors.Is(err, storage.ErrPresignNotSupported) {
+		t.Errorf("预签名上传返回 %v，期望 ErrPresignNotSupported", err)
+	}
+	if _, _, err := s.GeneratePresignedDownloadURL(ctx, "a.txt", time.Hour); !errors.Is(err, storage.ErrPresignNotSupported) {
+		t.Errorf("未设置代理链接时预签名下载返回 %v，期望 ErrPresignNotSupported", err)
+	}
+
+	s.SetProxyURLFunc(func(objectName string, expiration time.Duration) (string, error) {
+		return "https://upload.example.com/api/proxy/" + objectName, nil
+	})
+	proxyURL, _, err := s.GeneratePresignedDownloadURL(ctx, "/docs/a.txt", time.Hour)
+	if err != nil {
+		t.Fatalf("生成代理下载链接失败: %v", err)
+	}
+	if proxyURL != "https://upload.example.com/api/proxy/docs/a.txt" {
+		t.Errorf("代理下载链接为 %s", proxyURL)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	s := newTestService(t)
+
+	if err := s.Probe(context.Background()); err != nil {
+		t.Errorf("检查可用性失败: %v", err)
+	}
+
+	s.config.Password = "wrong"
+	if err := s.Probe(context.Background()); statusCode(err) != http.StatusUnauthorized {
+		t.Errorf("密码错误时返回 %v，期望401", err)
+	}
+}
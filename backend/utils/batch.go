@@ -0,0 +1,312 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 批量上传中单个文件的状态
+const (
+	BatchFilePending   = "pending"   // 等待上传
+	BatchFileUploading = "uploading" // 上传中
+	BatchFileSucceeded = "succeeded" // 上传成功
+	BatchFileSkipped   = "skipped"   // 文件已存在，跳过上传
+	BatchFileFailed    = "failed"    // 上传失败
+)
+
+// 批量上传相关错误定义
+var (
+	// ErrBatchNotFound 批量上传会话不存在
+	ErrBatchNotFound = errors.New("批量上传会话不存在")
+
+	// ErrBatchEmpty 批量上传清单为空
+	ErrBatchEmpty = errors.New("批量上传清单为空")
+)
+
+// BatchFile 批量上传中的单个文件
+type BatchFile struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	UploadID    string `json:"uploadID,omitempty"`
+	Status      string `json:"status"`
+	Received    int64  `json:"received"`
+	Transferred int64  `json:"transferred"`
+	Error       string `json:"error,omitempty"`
+}
+
+// BatchSummary 批量上传的汇总信息
+type BatchSummary struct {
+	ID          string      `json:"id"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	TotalFiles  int         `json:"totalFiles"`
+	TotalBytes  int64       `json:"totalBytes"`
+	Received    int64       `json:"received"`    // 服务器已接收的字节数
+	Transferred int64       `json:"transferred"` // 已上传到存储服务的字节数（跳过的文件按完整大小计算）
+	Pending     int         `json:"pending"`
+	Uploading   int         `json:"uploading"`
+	Succeeded   int         `json:"succeeded"`
+	Skipped     int         `json:"skipped"`
+	Failed      int         `json:"failed"`
+	Finished    bool        `json:"finished"`
+	Files       []BatchFile `json:"files"`
+}
+
+// batch 批量上传会话
+type batch struct {
+	id        string
+	createdAt time.Time
+	updatedAt time.Time
+	files     []*BatchFile
+	index     map[string]*BatchFile // 文件名 -> 文件
+	done      chan struct{}         // 会话被清理时关闭，用于结束进度订阅协程
+}
+
+// BatchManager 批量上传会话管理器
+// 每个文件的上传仍然使用独立的上传ID，管理器订阅这些上传ID的进度，
+// 汇总后以批量会话ID发布到进度管理器，复用WebSocket、SSE和轮询接口
+type BatchManager struct {
+	mutex    sync.Mutex
+	batches  map[string]*batch
+	progress *ProgressManager
+	logger   *Logger
+}
+
+// NewBatchManager 创建新的批量上传会话管理器
+func NewBatchManager(progress *ProgressManager, logger *Logger) *BatchManager {
+	return &BatchManager{
+		batches:  make(map[string]*batch),
+		progress: progress,
+		logger:   logger,
+	}
+}
+
+// CreateBatch 根据文件清单创建批量上传会话，返回会话的汇总信息
+func (m *BatchManager) CreateBatch(files []BatchFile) (*BatchSummary, error) {
+	if len(files) == 0 {
+		return nil, ErrBatchEmpty
+	}
+
+	now := time.Now()
+	b := &batch{
+		id:        "batch_" + randomID(),
+		createdAt: now,
+		updatedAt: now,
+		index:     make(map[string]*BatchFile, len(files)),
+		done:      make(chan struct{}),
+	}
+	for _, f := range files {
+		if f.Name == "" {
+			return nil, fmt.Errorf("文件名不能为空")
+		}
+		if f.Size < 0 {
+			return nil, fmt.Errorf("文件大小不能为负数: %s", f.Name)
+		}
+		if _, exists := b.index[f.Name]; exists {
+			return nil, fmt.Errorf("文件清单中存在重复的文件: %s", f.Name)
+		}
+		file := &BatchFile{
+			Name:   f.Name,
+			Size:   f.Size,
+			Status: BatchFilePending,
+		}
+		b.files = append(b.files, file)
+		b.index[f.Name] = file
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.batches[b.id] = b
+	summary := b.summary()
+	m.progress.UpdateBatch(b.id, summary)
+
+	return summary, nil
+}
+
+// Attach 将一次文件上传关联到批量会话，并开始汇总该上传ID的进度
+// 应在接收请求体之前调用，使接收阶段的进度也计入会话；只汇总关联之后产生的进度帧，
+// 上传ID被重复使用时，上一次上传留下的终止状态不会影响本次上传。清单中不存在的文件会被追加到会话中
+func (m *BatchManager) Attach(batchID string, fileName string, size int64, uploadID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b, ok := m.batches[batchID]
+	if !ok {
+		return ErrBatchNotFound
+	}
+
+	file := b.file(fileName, size, m.logger)
+
+	// 重新上传（例如失败后重试）时重置文件状态
+	file.UploadID = uploadID
+	file.Status = BatchFileUploading
+	file.Received = 0
+	file.Transferred = 0
+	file.Error = ""
+	if file.Size == 0 {
+		file.Size = size
+	}
+	m.publish(b)
+
+	sub := m.progress.SubscribeUpdates(uploadID)
+	go m.watch(b, file, uploadID, sub)
+
+	return nil
+}
+
+// FailFile 将还没有关联上传的文件标记为失败，用于上传请求在关联会话之前就被拒绝的情况（例如上传队列已满）
+// 客户端重新上传时再次关联会重置文件状态
+func (m *BatchManager) FailFile(batchID string, fileName string, err error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b, ok := m.batches[batchID]
+	if !ok {
+		return ErrBatchNotFound
+	}
+
+	file := b.file(fileName, 0, m.logger)
+	file.UploadID = ""
+	file.Status = BatchFileFailed
+	file.Received = 0
+	file.Transferred = 0
+	file.Error = err.Error()
+	m.publish(b)
+
+	return nil
+}
+
+// file 返回会话中的文件，清单中不存在时追加到会话，调用方需持有锁
+func (b *batch) file(fileName string, size int64, logger *Logger) *BatchFile {
+	file, exists := b.index[fileName]
+	if !exists {
+		logger.Printf("文件不在批量上传清单中，追加到会话: 会话=%s, 文件=%s", b.id, fileName)
+		file = &BatchFile{Name: fileName, Size: size}
+		b.files = append(b.files, file)
+		b.index[fileName] = file
+	}
+	return file
+}
+
+// watch 消费单个文件上传的进度帧并更新会话，直到收到终止状态
+func (m *BatchManager) watch(b *batch, file *BatchFile, uploadID string, sub *Subscription) {
+	defer sub.Close()
+
+	for {
+		select {
+		case progress := <-sub.C():
+			if m.applyProgress(b, file, uploadID, progress) {
+				return
+			}
+		case <-sub.Done():
+			return
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// applyProgress 将一帧进度应用到文件状态，返回是否已到达终止状态
+func (m *BatchManager) applyProgress(b *batch, file *BatchFile, uploadID string, progress *ProgressInfo) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// 文件已被新的上传重新关联，忽略旧上传的进度
+	if file.UploadID != uploadID {
+		return true
+	}
+
+	file.Received = progress.Received
+	file.Transferred = progress.Transferred
+
+	switch progress.Phase {
+	case PhaseDone:
+		file.Status = BatchFileSucceeded
+		if progress.Skipped {
+			file.Status = BatchFileSkipped
+		}
+		file.Transferred = file.Size
+	case PhaseFailed:
+		file.Status = BatchFileFailed
+		file.Error = progress.Error
+	}
+	m.publish(b)
+
+	return progress.IsFinal()
+}
+
+// publish 发布会话的汇总进度，调用方需持有锁
+func (m *BatchManager) publish(b *batch) {
+	b.updatedAt = time.Now()
+	summary := b.summary()
+	m.progress.UpdateBatch(b.id, summary)
+	if summary.Finished {
+		m.logger.Printf("批量上传完成: 会话=%s, 成功=%d, 跳过=%d, 失败=%d",
+			b.id, summary.Succeeded, summary.Skipped, summary.Failed)
+	}
+}
+
+// GetBatch 获取批量会话的汇总信息
+func (m *BatchManager) GetBatch(batchID string) (*BatchSummary, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b, ok := m.batches[batchID]
+	if !ok {
+		return nil, false
+	}
+	return b.summary(), true
+}
+
+// CleanupStale 清理超过指定时长未更新的批量会话
+func (m *BatchManager) CleanupStale(maxAge time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	for id, b := range m.batches {
+		if b.updatedAt.Before(cutoff) {
+			close(b.done)
+			delete(m.batches, id)
+		}
+	}
+}
+
+// summary 生成会话的汇总信息，调用方需持有锁
+func (b *batch) summary() *BatchSummary {
+	s := &BatchSummary{
+		ID:         b.id,
+		CreatedAt:  b.createdAt,
+		TotalFiles: len(b.files),
+		Files:      make([]BatchFile, 0, len(b.files)),
+	}
+
+	for _, f := range b.files {
+		s.TotalBytes += f.Size
+		s.Files = append(s.Files, *f)
+
+		switch f.Status {
+		case BatchFilePending:
+			s.Pending++
+		case BatchFileUploading:
+			s.Uploading++
+			s.Received += f.Received
+			s.Transferred += f.Transferred
+		case BatchFileSucceeded:
+			s.Succeeded++
+			s.Received += f.Size
+			s.Transferred += f.Size
+		case BatchFileSkipped:
+			s.Skipped++
+			s.Received += f.Size
+			s.Transferred += f.Size
+		case BatchFileFailed:
+			s.Failed++
+		}
+	}
+	s.Finished = s.Pending == 0 && s.Uploading == 0
+
+	return s
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package utils
+
+import "errors"
+
+// FreeDiskSpace 当前平台不支持查询可用磁盘空间
+func FreeDiskSpace(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package utils
+
+import "syscall"
+
+// FreeDiskSpace 返回目录所在文件系统中非特权用户可用的字节数
+func FreeDiskSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
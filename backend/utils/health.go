@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MinFreeDiskFromEnv 返回就绪检查要求的临时目录最小可用空间（字节）
+// 通过环境变量 HEALTH_MIN_FREE_MB 配置，默认512MB
+func MinFreeDiskFromEnv() uint64 {
+	return uint64(envInt("HEALTH_MIN_FREE_MB", 512)) * 1024 * 1024
+}
+
+// CheckDiskSpace 检查目录所在磁盘的可用空间不少于minFree字节，返回可用空间
+// 当前平台不支持查询时返回 errors.ErrUnsupported
+func CheckDiskSpace(dir string, minFree uint64) (uint64, error) {
+	free, err := FreeDiskSpace(dir)
+	if err != nil {
+		return 0, err
+	}
+	if free < minFree {
+		return free, fmt.Errorf("可用空间不足: %d MB，至少需要 %d MB", free/1024/1024, minFree/1024/1024)
+	}
+	return free, nil
+}
+
+// CheckWritable 通过创建并删除临时文件检查目录是否可写
+func CheckWritable(dir string) error {
+	file, err := os.CreateTemp(dir, ".ready-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	return errors.Join(file.Close(), os.Remove(name))
+}
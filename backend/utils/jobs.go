@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 异步上传任务的状态
+const (
+	JobPending   = "pending"   // 已接收文件，等待上传到存储服务
+	JobRunning   = "running"   // 正在上传到存储服务
+	JobSucceeded = "succeeded" // 上传成功
+	JobFailed    = "failed"    // 上传失败或被取消
+)
+
+// 异步上传任务相关错误定义
+var (
+	// ErrJobNotFound 异步上传任务不存在
+	ErrJobNotFound = errors.New("异步上传任务不存在")
+)
+
+// UploadJob 异步上传任务
+// 文件保存到临时目录后即返回任务ID，由后台协程完成向存储服务的上传
+type UploadJob struct {
+	ID         string      `json:"id"`
+	UploadID   string      `json:"uploadID"`
+	ObjectName string      `json:"objectName"`
+	LocalFile  string      `json:"localFile"`
+	Size       int64       `json:"size"`
+	Backend    string      `json:"backend,omitempty"`   // 存储后端名称，为空表示默认后端
+	ClientIP   string      `json:"clientIP,omitempty"`  // 发起上传的客户端IP，用于按IP限速
+	RateLimit  int         `json:"rateLimit,omitempty"` // 客户端要求的上传限速 (KB/s)
+	State      string      `json:"state"`
+	URL        string      `json:"url,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Attempts   int         `json:"attempts"` // 已开始执行的次数，服务重启后恢复执行时增加
+	CreatedAt  time.Time   `json:"createdAt"`
+	UpdatedAt  time.Time   `json:"updatedAt"`
+}
+
+// IsFinished 返回任务是否已结束
+func (j *UploadJob) IsFinished() bool {
+	return j.State == JobSucceeded || j.State == JobFailed
+}
+
+// JobManager 异步上传任务管理器
+// 每个任务以JSON文件保存在指定目录中，服务重启后可以恢复未完成的任务
+type JobManager struct {
+	mutex sync.Mutex
+	dir   string
+	jobs  map[string]*UploadJob
+}
+
+// NewJobManager 创建异步上传任务管理器，并加载目录中已保存的任务
+func NewJobManager(dir string) (*JobManager, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建任务目录失败: %w", err)
+	}
+
+	m := &JobManager{
+		dir:  dir,
+		jobs: make(map[string]*UploadJob),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取任务目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取任务文件失败: %w", err)
+		}
+		var job UploadJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("解析任务文件失败: %s, %w", entry.Name(), err)
+		}
+		m.jobs[job.ID] = &job
+	}
+
+	return m, nil
+}
+
+// CreateJob 根据上传信息创建并保存新的异步上传任务，任务ID、状态和时间由管理器填写
+func (m *JobManager) CreateJob(spec UploadJob) (*UploadJob, error) {
+	now := time.Now()
+	job := &spec
+	job.ID = "job_" + randomID()
+	job.State = JobPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.save(job); err != nil {
+		return nil, err
+	}
+	m.jobs[job.ID] = job
+
+	copied := *job
+	return &copied, nil
+}
+
+// UpdateJob 修改任务并保存，任务变为失败状态时删除临时文件（失败是最终状态，不会再重试）
+func (m *JobManager) UpdateJob(id string, apply func(job *UploadJob)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	updated := *job
+	apply(&updated)
+	updated.UpdatedAt = time.Now()
+	if err := m.save(&updated); err != nil {
+		return err
+	}
+	m.jobs[id] = &updated
+
+	if updated.State == JobFailed && job.State != JobFailed {
+		removeLocalFile(updated.LocalFile)
+	}
+
+	return nil
+}
+
+// GetJob 获取任务的副本
+func (m *JobManager) GetJob(id string) (*UploadJob, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *job
+	return &copied, true
+}
+
+// UnfinishedJobs 返回所有未结束的任务，按创建时间排序
+func (m *JobManager) UnfinishedJobs() []*UploadJob {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var jobs []*UploadJob
+	for _, job := range m.jobs {
+		if !job.IsFinished() {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// CleanupStale 清理结束超过指定时长的任务及其遗留的临时文件
+func (m *JobManager) CleanupStale(maxAge time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	for id, job := range m.jobs {
+		if job.IsFinished() && job.UpdatedAt.Before(cutoff) {
+			removeLocalFile(job.LocalFile)
+			_ = os.Remove(m.path(id))
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// removeLocalFile 删除任务的临时文件，文件已被删除时忽略
+func removeLocalFile(path string) {
+	if path == "" {
+		return
+	}
+	_ = os.Remove(path)
+}
+
+// save 将任务写入文件，先写临时文件再重命名，避免中途崩溃留下不完整的文件，调用方需持有锁
+func (m *JobManager) save(job *UploadJob) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化任务失败: %w", err)
+	}
+
+	path := m.path(job.ID)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("保存任务失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("保存任务失败: %w", err)
+	}
+	return nil
+}
+
+// path 返回任务文件的路径
+func (m *JobManager) path(id string) string {
+	return filepath.Join(m.dir, id+".json")
+}
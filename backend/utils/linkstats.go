@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// 短链接访问结果
+const (
+	AccessResultRedirected        = "redirected"         // 已重定向到下载地址
+	AccessResultViewed            = "viewed"             // 已展示文件详情页
+	AccessResultExpired           = "expired"            // 链接已过期
+	AccessResultNotFound          = "not_found"          // 链接不存在
+	AccessResultExhausted         = "exhausted"          // 下载次数已用完
+	AccessResultPasswordRequired  = "password_required"  // 未提供访问密码
+	AccessResultPasswordIncorrect = "password_incorrect" // 访问密码错误
+	AccessResultTooManyAttempts   = "too_many_attempts"  // 密码尝试次数过多
+)
+
+// 每个短链接保留的最近访问记录数量
+const maxRecentAccesses = 100
+
+// 最多统计的短链接数量，超出时淘汰最久未被访问的统计数据
+const maxTrackedLinks = 10000
+
+// LinkAccess 单次短链接访问记录
+type LinkAccess struct {
+	Time      time.Time `json:"time"`
+	ClientIP  string    `json:"clientIP"`
+	UserAgent string    `json:"userAgent"`
+	Referer   string    `json:"referer"`
+	Result    string    `json:"result"`
+}
+
+// LinkStats 短链接访问统计
+type LinkStats struct {
+	UniqueID    string         `json:"uniqueID"`
+	Total       int            `json:"total"`
+	Counters    map[string]int `json:"counters"` // 按访问结果统计的次数
+	FirstAccess time.Time      `json:"firstAccess"`
+	LastAccess  time.Time      `json:"lastAccess"`
+	Recent      []LinkAccess   `json:"recent"` // 最近的访问记录，按时间倒序
+}
+
+// LinkStatsManager 短链接访问统计管理器
+type LinkStatsManager struct {
+	mutex sync.RWMutex
+	stats map[string]*LinkStats // UniqueID -> LinkStats
+}
+
+// NewLinkStatsManager 创建新的短链接访问统计管理器
+func NewLinkStatsManager() *LinkStatsManager {
+	return &LinkStatsManager{
+		stats: make(map[string]*LinkStats),
+	}
+}
+
+// RecordAccess 记录一次短链接访问
+// 不存在的链接不记录，避免随意构造的ID占满内存
+func (m *LinkStatsManager) RecordAccess(uniqueID string, access LinkAccess) {
+	if access.Result == AccessResultNotFound {
+		return
+	}
+	if access.Time.IsZero() {
+		access.Time = time.Now()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats, ok := m.stats[uniqueID]
+	if !ok {
+		if len(m.stats) >= maxTrackedLinks {
+			m.evictOldest()
+		}
+		stats = &LinkStats{
+			UniqueID:    uniqueID,
+			Counters:    make(map[string]int),
+			FirstAccess: access.Time,
+		}
+		m.stats[uniqueID] = stats
+	}
+
+	stats.Total++
+	stats.Counters[access.Result]++
+	stats.LastAccess = access.Time
+
+	// 只保留最近的访问记录，超出上限时丢弃最旧的
+	stats.Recent = append(stats.Recent, access)
+	if len(stats.Recent) > maxRecentAccesses {
+		stats.Recent = stats.Recent[len(stats.Recent)-maxRecentAccesses:]
+	}
+}
+
+// evictOldest 淘汰最久未被访问的统计数据，调用方需持有写锁
+func (m *LinkStatsManager) evictOldest() {
+	var oldestID string
+	var oldest time.Time
+	for id, stats := range m.stats {
+		if oldestID == "" || stats.LastAccess.Before(oldest) {
+			oldestID, oldest = id, stats.LastAccess
+		}
+	}
+	delete(m.stats, oldestID)
+}
+
+// GetStats 获取短链接访问统计的副本，limit限制返回的最近访问记录数量（0表示全部）
+func (m *LinkStatsManager) GetStats(uniqueID string, limit int) (*LinkStats, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stats, ok := m.stats[uniqueID]
+	if !ok {
+		return nil, false
+	}
+
+	result := &LinkStats{
+		UniqueID:    stats.UniqueID,
+		Total:       stats.Total,
+		Counters:    make(map[string]int, len(stats.Counters)),
+		FirstAccess: stats.FirstAccess,
+		LastAccess:  stats.LastAccess,
+	}
+	for k, v := range stats.Counters {
+		result.Counters[k] = v
+	}
+
+	// 按时间倒序返回最近的访问记录
+	count := len(stats.Recent)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+	result.Recent = make([]LinkAccess, 0, count)
+	for i := len(stats.Recent) - 1; i >= 0 && len(result.Recent) < count; i-- {
+		result.Recent = append(result.Recent, stats.Recent[i])
+	}
+
+	return result, true
+}
+
+// CleanupStale 清理最后访问时间早于指定时长的统计数据
+func (m *LinkStatsManager) CleanupStale(retention time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cutoff := time.Now().Add(-retention)
+	for id, stats := range m.stats {
+		if stats.LastAccess.Before(cutoff) {
+			delete(m.stats, id)
+		}
+	}
+}
@@ -1,30 +1,123 @@
 package utils
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// WebSocket连接相关参数
+const (
+	writeWait        = 10 * time.Second  // 单次写入的超时时间
+	pongWait         = 60 * time.Second  // 等待客户端pong的超时时间
+	pingPeriod       = pongWait * 9 / 10 // 发送ping的间隔，必须小于pongWait
+	maxClientMessage = 4096              // 客户端消息的最大字节数
+	subscriberQueue  = 16                // 每个订阅者的发送队列长度
+)
+
+// speedTau 速度指数加权移动平均的时间常数，越大越平滑
+const speedTau = 3 * time.Second
+
+// 上传阶段
+const (
+	PhaseReceiving = "receiving" // 浏览器→服务器：接收请求体
+	PhaseStaging   = "staging"   // 保存到服务器临时目录
+	PhaseQueued    = "queued"    // 等待上传调度器分配执行资格
+	PhaseUploading = "uploading" // 服务器→存储服务
+	PhasePaused    = "paused"    // 客户端暂停，等待恢复
+	PhaseVerifying = "verifying" // 校验存储服务中的对象
+	PhaseDone      = "done"      // 上传完成
+	PhaseFailed    = "failed"    // 上传失败
+)
+
 // ProgressInfo 进度信息结构体
+// Received/ReceiveTotal 为浏览器→服务器阶段的字节数，Transferred/Total 为服务器→存储服务阶段的字节数，
+// Percentage、Speed 和 ETA 对应当前所处阶段
 type ProgressInfo struct {
-	FileName    string  `json:"fileName"`
-	Increment   int64   `json:"increment"`
-	Transferred int64   `json:"transferred"`
-	Total       int64   `json:"total"`
-	Percentage  int     `json:"percentage"`
-	Speed       float64 `json:"speed"` // 上传速度 (KB/s)
+	FileName      string        `json:"fileName"`
+	Phase         string        `json:"phase"`
+	StartTime     time.Time     `json:"startTime"`
+	Increment     int64         `json:"increment"`
+	Received      int64         `json:"received"`
+	ReceiveTotal  int64         `json:"receiveTotal"`
+	Transferred   int64         `json:"transferred"`
+	Total         int64         `json:"total"`
+	Percentage    int           `json:"percentage"`
+	QueuePosition int           `json:"queuePosition,omitempty"` // 排队时在上传队列中的位置，从1开始
+	Speed         float64       `json:"speed"`                   // 当前阶段的平滑速度 (KB/s)
+	ETA           float64       `json:"eta"`                     // 当前阶段的预计剩余时间（秒），-1表示未知
+	Result        interface{}   `json:"result,omitempty"`        // 上传完成时的结果
+	Error         string        `json:"error,omitempty"`         // 上传失败时的错误信息
+	Skipped       bool          `json:"skipped,omitempty"`       // 文件已存在，跳过了上传
+	Batch         *BatchSummary `json:"batch,omitempty"`         // 批量上传会话的汇总信息
+}
+
+// IsFinal 返回进度是否已处于完成或失败的终止状态
+func (p *ProgressInfo) IsFinal() bool {
+	return p.Phase == PhaseDone || p.Phase == PhaseFailed
+}
+
+// Subscription 进度订阅，每个订阅者拥有独立的有界发送队列
+// 队列满时丢弃最旧的进度帧，保证慢速订阅者不会阻塞进度更新
+type Subscription struct {
+	uploadID string
+	queue    chan *ProgressInfo
+	done     chan struct{}
+	once     sync.Once
+	manager  *ProgressManager
+}
+
+// C 返回接收进度信息的通道
+func (s *Subscription) C() <-chan *ProgressInfo {
+	return s.queue
+}
+
+// Done 返回订阅关闭时被关闭的通道
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
 }
 
-// ProgressManager 上传进度管理器
+// Close 取消订阅，可重复调用
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.manager.unsubscribe(s)
+		close(s.done)
+	})
+}
+
+// publish 非阻塞地投递进度帧，队列满时丢弃最旧的一帧
+func (s *Subscription) publish(progress *ProgressInfo) {
+	for {
+		select {
+		case s.queue <- progress:
+			return
+		default:
+		}
+
+		// 队列已满，丢弃最旧的一帧后重试
+		select {
+		case <-s.queue:
+		default:
+		}
+	}
+}
+
+// uploadProgress 单个上传任务的进度状态及订阅者
+type uploadProgress struct {
+	progress    *ProgressInfo
+	lastTime    time.Time // 上次更新时间，用于计算速度
+	subscribers map[*Subscription]struct{}
+}
+
+// ProgressManager 上传进度管理器，按上传ID分发进度的发布/订阅中心
 type ProgressManager struct {
-	mutex      sync.Mutex
-	conns      map[string]*websocket.Conn
-	progresses map[string]*ProgressInfo
-	lastTimes  map[string]time.Time // 上次更新时间，用于计算速度
-	logger     *Logger              // 使用我们的自定义日志器
+	mutex   sync.Mutex
+	uploads map[string]*uploadProgress
+	logger  *Logger // 使用我们的自定义日志器
 }
 
 // NewProgressManager 创建新的进度管理器
@@ -36,69 +129,321 @@ func NewProgressManager(logger *Logger) *ProgressManager {
 	}
 
 	return &ProgressManager{
-		conns:      make(map[string]*websocket.Conn),
-		progresses: make(map[string]*ProgressInfo),
-		lastTimes:  make(map[string]time.Time),
-		logger:     logger,
+		uploads: make(map[string]*uploadProgress),
+		logger:  logger,
+	}
+}
+
+// getOrCreate 获取上传任务状态，不存在时创建，调用方需持有锁
+func (m *ProgressManager) getOrCreate(id string) *uploadProgress {
+	upload, ok := m.uploads[id]
+	if !ok {
+		upload = &uploadProgress{
+			subscribers: make(map[*Subscription]struct{}),
+		}
+		m.uploads[id] = upload
 	}
+	return upload
+}
+
+// Subscribe 订阅指定上传ID的进度，同一上传ID可以有任意数量的订阅者
+// 如果已有进度信息，会立即投递最新的一帧
+func (m *ProgressManager) Subscribe(id string) *Subscription {
+	return m.subscribe(id, true)
 }
 
-// RegisterConn 注册新连接
-func (m *ProgressManager) RegisterConn(id string, conn *websocket.Conn) {
+// SubscribeUpdates 订阅指定上传ID在订阅之后产生的进度，不投递已有的进度信息
+// 上传ID被重复使用时，上一次上传留下的完成或失败状态不会被当作本次上传的结果
+func (m *ProgressManager) SubscribeUpdates(id string) *Subscription {
+	return m.subscribe(id, false)
+}
+
+// subscribe 添加订阅者，replay为true时立即投递最新的一帧
+func (m *ProgressManager) subscribe(id string, replay bool) *Subscription {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
-	m.conns[id] = conn
+
+	sub := &Subscription{
+		uploadID: id,
+		queue:    make(chan *ProgressInfo, subscriberQueue),
+		done:     make(chan struct{}),
+		manager:  m,
+	}
+
+	upload := m.getOrCreate(id)
+	upload.subscribers[sub] = struct{}{}
+	if replay && upload.progress != nil {
+		sub.publish(upload.progress)
+	}
+
+	return sub
 }
 
-// RemoveConn 删除连接
-func (m *ProgressManager) RemoveConn(id string) {
+// unsubscribe 移除订阅者
+func (m *ProgressManager) unsubscribe(sub *Subscription) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
-	delete(m.conns, id)
-	delete(m.progresses, id)
-	delete(m.lastTimes, id)
+
+	if upload, ok := m.uploads[sub.uploadID]; ok {
+		delete(upload.subscribers, sub)
+	}
+}
+
+// ServeWebSocket 通过WebSocket连接推送指定上传ID的进度，阻塞直到连接关闭
+// 每个连接由独立的写协程负责发送进度和ping保活，读循环负责处理pong、检测断开，
+// 并将客户端发送的消息（例如取消、暂停等控制消息）交给onMessage处理
+func (m *ProgressManager) ServeWebSocket(id string, conn *websocket.Conn, onMessage func(message []byte)) {
+	sub := m.Subscribe(id)
+	defer sub.Close()
+
+	go m.writePump(sub, conn)
+
+	// 读循环：处理pong、客户端消息并检测客户端断开
+	conn.SetReadLimit(maxClientMessage)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			m.logger.Printf("WebSocket连接关闭: ID=%s, 错误: %v", id, err)
+			return
+		}
+		if messageType == websocket.TextMessage && onMessage != nil {
+			onMessage(message)
+		}
+	}
 }
 
-// UpdateProgress 更新进度并发送给客户端
+// writePump 将订阅队列中的进度写入WebSocket连接，并定期发送ping
+func (m *ProgressManager) writePump(sub *Subscription, conn *websocket.Conn) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = conn.Close()
+	}()
+
+	for {
+		select {
+		case progress := <-sub.C():
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(progress); err != nil {
+				m.logger.Printf("发送进度信息失败: ID=%s, %v", sub.uploadID, err)
+				sub.Close()
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				sub.Close()
+				return
+			}
+		case <-sub.Done():
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			_ = conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+// UpdateProgress 更新服务器→存储服务阶段的进度并分发给所有订阅者
+// 分发是非阻塞的，慢速订阅者只会丢失中间的进度帧
 func (m *ProgressManager) UpdateProgress(id string, fileName string, increment, transferred, total int64) {
+	m.update(id, increment, func(p *ProgressInfo) {
+		p.FileName = fileName
+		p.Phase = PhaseUploading
+		p.Transferred = transferred
+		p.Total = total
+	})
+}
+
+// UpdateReceived 更新浏览器→服务器阶段（接收请求体）的进度
+func (m *ProgressManager) UpdateReceived(id string, increment, received, total int64) {
+	m.update(id, increment, func(p *ProgressInfo) {
+		p.Phase = PhaseReceiving
+		p.Received = received
+		p.ReceiveTotal = total
+	})
+}
+
+// SetPhase 切换上传阶段，不改变字节计数
+func (m *ProgressManager) SetPhase(id string, fileName string, phase string) {
+	m.update(id, 0, func(p *ProgressInfo) {
+		if fileName != "" {
+			p.FileName = fileName
+		}
+		p.Phase = phase
+	})
+}
+
+// SetQueued 标记上传正在排队，并更新在上传队列中的位置
+func (m *ProgressManager) SetQueued(id string, fileName string, position int) {
+	m.update(id, 0, func(p *ProgressInfo) {
+		if fileName != "" {
+			p.FileName = fileName
+		}
+		p.Phase = PhaseQueued
+		p.QueuePosition = position
+	})
+}
+
+// Complete 发送上传完成的终止状态，携带上传结果
+func (m *ProgressManager) Complete(id string, fileName string, result interface{}) {
+	m.update(id, 0, func(p *ProgressInfo) {
+		if fileName != "" {
+			p.FileName = fileName
+		}
+		p.Phase = PhaseDone
+		p.Result = result
+	})
+}
+
+// Skip 发送文件已存在、跳过上传的终止状态
+func (m *ProgressManager) Skip(id string, fileName string, result interface{}) {
+	m.update(id, 0, func(p *ProgressInfo) {
+		if fileName != "" {
+			p.FileName = fileName
+		}
+		p.Phase = PhaseDone
+		p.Skipped = true
+		p.Result = result
+	})
+}
+
+// UpdateBatch 发布批量上传会话的汇总进度
+// 字节计数取自汇总信息，会话结束时发送携带汇总信息的终止状态
+func (m *ProgressManager) UpdateBatch(id string, summary *BatchSummary) {
+	var increment int64
+	if prev, ok := m.GetProgress(id); ok {
+		increment = summary.Transferred - prev.Transferred
+	}
+
+	m.update(id, increment, func(p *ProgressInfo) {
+		p.Phase = PhaseUploading
+		p.Received = summary.Received
+		p.ReceiveTotal = summary.TotalBytes
+		p.Transferred = summary.Transferred
+		p.Total = summary.TotalBytes
+		p.Batch = summary
+		p.Result = nil
+		p.Error = ""
+		if summary.Finished {
+			p.Phase = PhaseDone
+			p.Result = summary
+			if summary.Failed > 0 {
+				p.Phase = PhaseFailed
+				p.Error = fmt.Sprintf("%d 个文件上传失败", summary.Failed)
+			}
+		}
+	})
+}
+
+// Fail 发送上传失败的终止状态，携带错误信息
+func (m *ProgressManager) Fail(id string, fileName string, err error) {
+	m.update(id, 0, func(p *ProgressInfo) {
+		if fileName != "" {
+			p.FileName = fileName
+		}
+		p.Phase = PhaseFailed
+		if err != nil {
+			p.Error = err.Error()
+		}
+	})
+}
+
+// Reset 清除上传ID的进度信息，保留订阅者
+// 上传ID被重复使用时，新的上传从空白的进度开始，不会继承上一次上传的错误、跳过标记和结果
+func (m *ProgressManager) Reset(id string) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	// 计算百分比
-	percentage := 0
-	if total > 0 {
-		percentage = int(transferred * 100 / total)
+	if upload, ok := m.uploads[id]; ok {
+		upload.progress = nil
+		upload.lastTime = time.Time{}
 	}
+}
+
+// update 在上一帧的基础上生成新的进度帧，计算百分比、速度和剩余时间后分发给所有订阅者
+// 每次都生成新的进度帧，已投递给订阅者的帧不会被修改
+func (m *ProgressManager) update(id string, increment int64, apply func(p *ProgressInfo)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
-	// 计算上传速度 (KB/s)
-	var speed float64 = 0
+	upload := m.getOrCreate(id)
 	now := time.Now()
-	if lastTime, ok := m.lastTimes[id]; ok && increment > 0 {
-		elapsed := now.Sub(lastTime).Seconds()
+
+	progress := &ProgressInfo{StartTime: now}
+	if upload.progress != nil {
+		*progress = *upload.progress
+	}
+	prevPhase := progress.Phase
+	progress.Increment = increment
+	apply(progress)
+	if progress.Phase != PhaseQueued {
+		progress.QueuePosition = 0
+	}
+
+	// 计算当前阶段的百分比和剩余字节数
+	var remaining int64 = -1
+	switch progress.Phase {
+	case PhaseReceiving:
+		progress.Percentage = percentOf(progress.Received, progress.ReceiveTotal)
+		if progress.ReceiveTotal > 0 {
+			remaining = progress.ReceiveTotal - progress.Received
+		}
+	case PhaseUploading:
+		progress.Percentage = percentOf(progress.Transferred, progress.Total)
+		if progress.Total > 0 {
+			remaining = progress.Total - progress.Transferred
+		}
+	case PhaseDone:
+		progress.Percentage = 100
+	default:
+		progress.Percentage = percentOf(progress.Transferred, progress.Total)
+	}
+
+	// 阶段切换时重新计算速度，否则按指数加权移动平均平滑瞬时速度 (KB/s)
+	if progress.Phase != prevPhase {
+		progress.Speed = 0
+	} else if !upload.lastTime.IsZero() && increment > 0 {
+		elapsed := now.Sub(upload.lastTime).Seconds()
 		if elapsed > 0 {
-			speed = float64(increment) / elapsed / 1024 // 转换为KB/s
+			instant := float64(increment) / elapsed / 1024 // 转换为KB/s
+			if progress.Speed == 0 {
+				progress.Speed = instant
+			} else {
+				alpha := 1 - math.Exp(-elapsed/speedTau.Seconds())
+				progress.Speed += alpha * (instant - progress.Speed)
+			}
 		}
 	}
-	m.lastTimes[id] = now
+	if increment > 0 || progress.Phase != prevPhase {
+		upload.lastTime = now
+	}
 
-	// 更新进度信息
-	progress := &ProgressInfo{
-		FileName:    fileName,
-		Increment:   increment,
-		Transferred: transferred,
-		Total:       total,
-		Percentage:  percentage,
-		Speed:       speed,
+	// 根据平滑速度估算剩余时间
+	progress.ETA = -1
+	if progress.IsFinal() {
+		progress.ETA = 0
+	} else if remaining >= 0 && progress.Speed > 0 {
+		progress.ETA = float64(remaining) / 1024 / progress.Speed
 	}
 
-	m.progresses[id] = progress
+	upload.progress = progress
 
-	// 如果有连接，发送进度更新
-	if conn, ok := m.conns[id]; ok {
-		if err := conn.WriteJSON(progress); err != nil {
-			m.logger.Printf("发送进度信息失败: %v", err)
-		}
+	for sub := range upload.subscribers {
+		sub.publish(progress)
+	}
+}
+
+// percentOf 计算百分比，总数未知时返回0
+func percentOf(current, total int64) int {
+	if total <= 0 {
+		return 0
 	}
+	return int(current * 100 / total)
 }
 
 // GetProgress 获取进度信息
@@ -106,22 +451,40 @@ func (m *ProgressManager) GetProgress(id string) (*ProgressInfo, bool) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	progress, ok := m.progresses[id]
-	return progress, ok
+	upload, ok := m.uploads[id]
+	if !ok || upload.progress == nil {
+		return nil, false
+	}
+	return upload.progress, true
 }
 
-// ClearAll 清除所有连接和进度信息
-func (m *ProgressManager) ClearAll() {
+// CleanupStale 清理没有订阅者且超过指定时长未更新的进度信息
+func (m *ProgressManager) CleanupStale(maxAge time.Duration) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	// 关闭所有WebSocket连接
-	for _, conn := range m.conns {
-		_ = conn.Close()
+	cutoff := time.Now().Add(-maxAge)
+	for id, upload := range m.uploads {
+		if len(upload.subscribers) == 0 && upload.lastTime.Before(cutoff) {
+			delete(m.uploads, id)
+		}
 	}
+}
 
-	// 清空所有映射
-	m.conns = make(map[string]*websocket.Conn)
-	m.progresses = make(map[string]*ProgressInfo)
-	m.lastTimes = make(map[string]time.Time)
+// ClearAll 关闭所有订阅并清除进度信息
+func (m *ProgressManager) ClearAll() {
+	m.mutex.Lock()
+	var subs []*Subscription
+	for _, upload := range m.uploads {
+		for sub := range upload.subscribers {
+			subs = append(subs, sub)
+		}
+	}
+	m.uploads = make(map[string]*uploadProgress)
+	m.mutex.Unlock()
+
+	// 在锁外关闭订阅，避免与unsubscribe重入死锁
+	for _, sub := range subs {
+		sub.Close()
+	}
 }
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"io"
+)
+
+// CountingReader 统计读取字节数的读取器，按一定间隔回调进度
+type CountingReader struct {
+	io.ReadCloser
+	total      int64
+	current    int64
+	lastReport int64
+	interval   int64
+	onProgress func(increment, current, total int64)
+}
+
+// NewCountingReader 创建统计读取字节数的读取器
+// total 为预期总字节数（未知时传入-1），每读取约1%的数据或读到末尾时回调一次
+func NewCountingReader(r io.ReadCloser, total int64, onProgress func(increment, current, total int64)) *CountingReader {
+	// 回调间隔为总大小的1%，至少64KB，避免过于频繁的更新
+	interval := int64(64 * 1024)
+	if total/100 > interval {
+		interval = total / 100
+	}
+
+	return &CountingReader{
+		ReadCloser: r,
+		total:      total,
+		interval:   interval,
+		onProgress: onProgress,
+	}
+}
+
+// Read 读取数据并统计字节数
+func (r *CountingReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		r.current += int64(n)
+	}
+	if r.onProgress != nil && r.current > r.lastReport &&
+		(r.current-r.lastReport >= r.interval || r.current == r.total || err == io.EOF) {
+		r.onProgress(r.current-r.lastReport, r.current, r.total)
+		r.lastReport = r.current
+	}
+	return
+}
+
+// BytesRead 返回已读取的字节数
+func (r *CountingReader) BytesRead() int64 {
+	return r.current
+}
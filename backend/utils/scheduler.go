@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// 上传调度相关错误定义
+var (
+	// ErrQueueFull 上传队列已满
+	ErrQueueFull = errors.New("上传队列已满，请稍后重试")
+)
+
+// SchedulerConfig 上传调度器配置
+type SchedulerConfig struct {
+	MaxConcurrent  int           // 同时向存储服务上传的最大文件数
+	MaxParts       int           // 所有上传合计的最大分片并行数
+	PartsPerUpload int           // 单个上传最多使用的分片并行数
+	MaxQueue       int           // 等待队列的最大长度，超过时拒绝新的上传
+	RetryAfter     time.Duration // 队列已满时建议客户端重试的间隔
+}
+
+// LoadSchedulerConfigFromEnv 从环境变量加载上传调度器配置，未设置的项使用默认值
+func LoadSchedulerConfigFromEnv() *SchedulerConfig {
+	// 尝试加载.env文件，但不强制要求
+	_ = godotenv.Load()
+
+	config := &SchedulerConfig{
+		MaxConcurrent:  envInt("UPLOAD_MAX_CONCURRENT", 4),
+		MaxParts:       envInt("UPLOAD_MAX_PARTS", 12),
+		PartsPerUpload: envInt("UPLOAD_PARTS_PER_UPLOAD", 3),
+		MaxQueue:       envInt("UPLOAD_MAX_QUEUE", 100),
+		RetryAfter:     time.Duration(envInt("UPLOAD_RETRY_AFTER", 30)) * time.Second,
+	}
+
+	if config.PartsPerUpload > config.MaxParts {
+		config.PartsPerUpload = config.MaxParts
+	}
+	return config
+}
+
+// envInt 读取正整数环境变量，未设置或不合法时返回默认值
+func envInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// SchedulerStats 上传调度器的运行状态
+type SchedulerStats struct {
+	Running    int `json:"running"`
+	Queued     int `json:"queued"`
+	PartsInUse int `json:"partsInUse"`
+	MaxRunning int `json:"maxRunning"`
+	MaxParts   int `json:"maxParts"`
+	MaxQueue   int `json:"maxQueue"`
+}
+
+// ticket 等待队列中的一个上传
+type ticket struct {
+	onQueued func(position int)
+	granted  chan int // 获得执行资格时写入分配到的分片并行数
+}
+
+// UploadScheduler 全局上传调度器
+// 限制同时向存储服务上传的文件数和合计分片并行数，超出的上传按先来先服务排队，
+// 排队期间通过回调通知当前位置
+type UploadScheduler struct {
+	mutex      sync.Mutex
+	config     *SchedulerConfig
+	running    int
+	partsInUse int
+	queue      *list.List // *ticket
+}
+
+// NewUploadScheduler 创建新的上传调度器
+func NewUploadScheduler(config *SchedulerConfig) *UploadScheduler {
+	return &UploadScheduler{
+		config: config,
+		queue:  list.New(),
+	}
+}
+
+// RetryAfter 返回队列已满时建议客户端重试的间隔
+func (s *UploadScheduler) RetryAfter() time.Duration {
+	return s.config.RetryAfter
+}
+
+// QueueFull 返回等待队列是否已满，用于在接收请求体之前尽早拒绝上传
+func (s *UploadScheduler) QueueFull() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return !s.canAdmit() && s.queue.Len() >= s.config.MaxQueue
+}
+
+// Acquire 获取上传的执行资格，返回分配到的分片并行数和释放函数
+// 没有空闲资格时加入等待队列，队列位置变化时调用onQueued（位置从1开始）；
+// 上下文结束时离开队列并返回上下文的错误
+func (s *UploadScheduler) Acquire(ctx context.Context, onQueued func(position int)) (int, func(), error) {
+	s.mutex.Lock()
+	if s.queue.Len() == 0 && s.canAdmit() {
+		parts := s.admit()
+		s.mutex.Unlock()
+		return parts, s.releaseFunc(parts), nil
+	}
+	if s.queue.Len() >= s.config.MaxQueue {
+		s.mutex.Unlock()
+		return 0, nil, ErrQueueFull
+	}
+
+	t := &ticket{
+		onQueued: onQueued,
+		granted:  make(chan int, 1),
+	}
+	element := s.queue.PushBack(t)
+	if onQueued != nil {
+		onQueued(s.queue.Len())
+	}
+	s.mutex.Unlock()
+
+	select {
+	case parts := <-t.granted:
+		return parts, s.releaseFunc(parts), nil
+	case <-ctx.Done():
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		select {
+		case parts := <-t.granted:
+			// 离开队列的同时刚好获得了执行资格，归还资格
+			s.release(parts)
+		default:
+			s.queue.Remove(element)
+			s.notifyPositions()
+		}
+		return 0, nil, ctx.Err()
+	}
+}
+
+// Stats 返回调度器的运行状态
+func (s *UploadScheduler) Stats() SchedulerStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return SchedulerStats{
+		Running:    s.running,
+		Queued:     s.queue.Len(),
+		PartsInUse: s.partsInUse,
+		MaxRunning: s.config.MaxConcurrent,
+		MaxParts:   s.config.MaxParts,
+		MaxQueue:   s.config.MaxQueue,
+	}
+}
+
+// canAdmit 返回是否还有空闲的执行资格，调用方需持有锁
+func (s *UploadScheduler) canAdmit() bool {
+	return s.running < s.config.MaxConcurrent && s.partsInUse < s.config.MaxParts
+}
+
+// admit 占用一个执行资格并分配分片并行数，调用方需持有锁
+func (s *UploadScheduler) admit() int {
+	parts := s.config.PartsPerUpload
+	if available := s.config.MaxParts - s.partsInUse; parts > available {
+		parts = available
+	}
+	s.running++
+	s.partsInUse += parts
+	return parts
+}
+
+// releaseFunc 返回只生效一次的释放函数
+func (s *UploadScheduler) releaseFunc(parts int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			s.release(parts)
+		})
+	}
+}
+
+// release 归还执行资格并按顺序唤醒排队的上传，调用方需持有锁
+func (s *UploadScheduler) release(parts int) {
+	s.running--
+	s.partsInUse -= parts
+
+	dispatched := false
+	for s.queue.Len() > 0 && s.canAdmit() {
+		t := s.queue.Remove(s.queue.Front()).(*ticket)
+		t.granted <- s.admit()
+		dispatched = true
+	}
+	if dispatched {
+		s.notifyPositions()
+	}
+}
+
+// notifyPositions 通知队列中所有上传的当前位置，调用方需持有锁
+func (s *UploadScheduler) notifyPositions() {
+	position := 0
+	for e := s.queue.Front(); e != nil; e = e.Next() {
+		position++
+		if t := e.Value.(*ticket); t.onQueued != nil {
+			t.onQueued(position)
+		}
+	}
+}
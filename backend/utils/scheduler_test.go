@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queuedUpload 在后台排队的上传，记录收到的队列位置和获得的执行资格
+type queuedUpload struct {
+	mutex     sync.Mutex
+	positions []int
+	done      chan struct{}
+	parts     int
+	release   func()
+	err       error
+}
+
+// enqueue 在后台调用Acquire，等到上传进入队列后返回
+func enqueue(t *testing.T, ctx context.Context, s *UploadScheduler) *queuedUpload {
+	t.Helper()
+	u := &queuedUpload{done: make(chan struct{})}
+	queued := make(chan struct{}, 1)
+	go func() {
+		defer close(u.done)
+		u.parts, u.release, u.err = s.Acquire(ctx, func(position int) {
+			u.mutex.Lock()
+			u.positions = append(u.positions, position)
+			u.mutex.Unlock()
+			select {
+			case queued <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	select {
+	case <-queued:
+	case <-u.done:
+	case <-time.After(time.Second):
+		t.Fatal("上传没有进入队列")
+	}
+	return u
+}
+
+// wait 等待排队的上传获得执行资格或离开队列
+func (u *queuedUpload) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-u.done:
+	case <-time.After(time.Second):
+		t.Fatal("排队的上传没有结束等待")
+	}
+}
+
+func (u *queuedUpload) lastPosition() int {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.positions[len(u.positions)-1]
+}
+
+func TestSchedulerAllocatesParts(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SchedulerConfig
+		want   []int // 依次获得的分片并行数
+	}{
+		{"分片充足", SchedulerConfig{MaxConcurrent: 3, MaxParts: 12, PartsPerUpload: 3, MaxQueue: 1}, []int{3, 3, 3}},
+		{"最后一个上传分到剩余的分片", SchedulerConfig{MaxConcurrent: 3, MaxParts: 5, PartsPerUpload: 3, MaxQueue: 1}, []int{3, 2}},
+		{"分片用完后不再接收", SchedulerConfig{MaxConcurrent: 4, MaxParts: 4, PartsPerUpload: 2, MaxQueue: 1}, []int{2, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewUploadScheduler(&tt.config)
+			for i, want := range tt.want {
+				parts, _, err := s.Acquire(context.Background(), nil)
+				if err != nil || parts != want {
+					t.Fatalf("第%d个上传获得 %d 个分片, %v，期望 %d", i+1, parts, err, want)
+				}
+			}
+			if s.canAdmit() {
+				t.Errorf("没有空闲资格时仍然可以接收上传: %+v", s.Stats())
+			}
+		})
+	}
+}
+
+func TestSchedulerQueue(t *testing.T) {
+	s := NewUploadScheduler(&SchedulerConfig{MaxConcurrent: 1, MaxParts: 3, PartsPerUpload: 3, MaxQueue: 2, RetryAfter: 30 * time.Second})
+	ctx := context.Background()
+
+	_, releaseA, err := s.Acquire(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := enqueue(t, ctx, s)
+	cancelCtx, cancelC := context.WithCancel(ctx)
+	c := enqueue(t, cancelCtx, s)
+	if b.lastPosition() != 1 || c.lastPosition() != 2 {
+		t.Errorf("队列位置为 %v 和 %v，期望 1 和 2", b.positions, c.positions)
+	}
+
+	// 队列已满时拒绝新的上传，由接口返回429和Retry-After
+	if !s.QueueFull() {
+		t.Error("队列已满时QueueFull返回false")
+	}
+	if _, _, err := s.Acquire(ctx, nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("队列已满时返回 %v，期望 ErrQueueFull", err)
+	}
+	if s.RetryAfter() != 30*time.Second {
+		t.Errorf("RetryAfter = %v", s.RetryAfter())
+	}
+
+	// 释放后按先来先服务唤醒，后面的上传位置前移；重复释放只生效一次
+	releaseA()
+	releaseA()
+	b.wait(t)
+	if b.err != nil || b.parts != 3 {
+		t.Fatalf("排队的上传获得 %d 个分片, %v", b.parts, b.err)
+	}
+	if c.lastPosition() != 1 {
+		t.Errorf("前面的上传开始后位置为 %d，期望 1", c.lastPosition())
+	}
+	if stats := s.Stats(); stats.Running != 1 || stats.Queued != 1 || stats.PartsInUse != 3 {
+		t.Errorf("重复释放后的状态不正确: %+v", stats)
+	}
+	if s.QueueFull() {
+		t.Error("队列未满时QueueFull返回true")
+	}
+
+	// 排队时取消请求会离开队列
+	cancelC()
+	c.wait(t)
+	if !errors.Is(c.err, context.Canceled) {
+		t.Errorf("取消排队返回 %v，期望 context.Canceled", c.err)
+	}
+	if stats := s.Stats(); stats.Queued != 0 {
+		t.Errorf("取消后仍在队列中: %+v", stats)
+	}
+
+	b.release()
+	if stats := s.Stats(); stats.Running != 0 || stats.PartsInUse != 0 {
+		t.Errorf("全部释放后的状态不正确: %+v", stats)
+	}
+}
@@ -1,27 +1,142 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	mathrand "math/rand"
 	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 短链接访问相关错误定义
+var (
+	// ErrLinkNotFound 短链接不存在
+	ErrLinkNotFound = errors.New("链接不存在或已过期")
+
+	// ErrLinkExpired 短链接已过期
+	ErrLinkExpired = errors.New("链接已过期")
+
+	// ErrLinkExhausted 短链接下载次数已用完
+	ErrLinkExhausted = errors.New("链接下载次数已用完")
+
+	// ErrPasswordRequired 短链接需要访问密码
+	ErrPasswordRequired = errors.New("链接需要访问密码")
+
+	// ErrPasswordIncorrect 访问密码错误
+	ErrPasswordIncorrect = errors.New("访问密码错误")
+
+	// ErrTooManyAttempts 密码尝试次数过多
+	ErrTooManyAttempts = errors.New("密码尝试次数过多，请稍后再试")
+)
+
+// 密码尝试限制：在窗口期内，同一客户端对同一链接允许的尝试次数
+// 只按客户端限制，不限制链接的总尝试次数，避免任何人都能通过错误的尝试把真正的收件人锁在链接之外
+const (
+	passwordAttemptWindow    = 15 * time.Minute
+	maxPasswordAttemptsPerIP = 5
 )
 
 // ShortLink 短链接结构体
 type ShortLink struct {
-	ID         string    // 短链接ID (存储uniqueID部分)
-	UniqueID   string    // 唯一标识符
-	LongURL    string    // 长URL（预签名URL）
-	FileName   string    // 文件名
-	Expiration time.Time // 过期时间
+	ID           string    // 短链接ID (存储uniqueID部分)
+	UniqueID     string    // 唯一标识符
+	LongURL      string    // 长URL（预签名URL）
+	FileName     string    // 文件名
+	ObjectName   string    // 存储中的对象名，用于查询文件大小和类型
+	Backend      string    // 对象所在的存储后端名称，为空表示默认后端
+	Expiration   time.Time // 过期时间
+	LandingPage  bool      // 访问时是否先展示文件详情页而不是直接重定向
+	PasswordHash string    // 访问密码的bcrypt哈希，为空表示无需密码
+	MaxDownloads int       // 最大下载次数，0表示不限制
+	Downloads    int       // 已下载次数
+	OneTime      bool      // 是否为一次性链接
+	Objects      []string  // 文件集合中的对象名列表
+	Prefix       string    // 文件集合对应的对象前缀
+}
+
+// ShortLinkOptions 创建短链接时的可选访问控制参数
+type ShortLinkOptions struct {
+	Password     string // 访问密码，为空表示无需密码
+	MaxDownloads int    // 最大下载次数，0表示不限制
+	OneTime      bool   // 一次性链接，等价于最大下载次数为1
+	ObjectName   string // 存储中的对象名，为空时使用文件名
+	Backend      string // 对象所在的存储后端名称，为空表示默认后端
+	LandingPage  bool   // 访问时先展示文件详情页
+
+	// 以下两项二选一，用于创建包含多个文件的集合链接
+	Objects []string // 集合中的对象名列表
+	Prefix  string   // 集合对应的对象前缀
+}
+
+// IsPasswordProtected 返回链接是否设置了访问密码
+func (l *ShortLink) IsPasswordProtected() bool {
+	return l.PasswordHash != ""
+}
+
+// IsCollection 返回链接是否指向多个文件组成的集合
+func (l *ShortLink) IsCollection() bool {
+	return len(l.Objects) > 0 || l.Prefix != ""
+}
+
+// CollectionPrefix 返回前缀集合对应的目录，总是以 / 结尾，避免 photos 匹配到 photos-private/ 下的文件
+// 兼容创建时没有规范化前缀的链接；对象列表集合返回空字符串
+func (l *ShortLink) CollectionPrefix() string {
+	if l.Prefix == "" || strings.HasSuffix(l.Prefix, "/") {
+		return l.Prefix
+	}
+	return l.Prefix + "/"
+}
+
+// ContainsObject 判断对象是否属于该集合链接，前缀集合按目录匹配
+func (l *ShortLink) ContainsObject(objectName string) bool {
+	if l.Prefix != "" {
+		return strings.HasPrefix(objectName, l.CollectionPrefix())
+	}
+	for _, obj := range l.Objects {
+		if obj == objectName {
+			return true
+		}
+	}
+	return false
+}
+
+// RemainingDownloads 返回剩余下载次数，-1表示不限制
+func (l *ShortLink) RemainingDownloads() int {
+	if l.MaxDownloads <= 0 {
+		return -1
+	}
+	remaining := l.MaxDownloads - l.Downloads
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// isExhausted 判断链接下载次数是否已用完
+func (l *ShortLink) isExhausted() bool {
+	return l.MaxDownloads > 0 && l.Downloads >= l.MaxDownloads
+}
+
+// passwordAttempts 窗口期内的密码尝试次数
+type passwordAttempts struct {
+	count       int
+	windowStart time.Time
 }
 
 // ShortLinkManager 短链接管理器
 type ShortLinkManager struct {
 	mutex sync.RWMutex
 	links map[string]*ShortLink // UniqueID -> ShortLink
+
+	attemptMutex sync.Mutex
+	attempts     map[string]*passwordAttempts // UniqueID|客户端IP -> 尝试次数
 }
 
 // NewShortLinkManager 创建新的短链接管理器
@@ -29,7 +144,8 @@ func NewShortLinkManager() *ShortLinkManager {
 	// 初始化随机种子
 	mathrand.Seed(time.Now().UnixNano())
 	return &ShortLinkManager{
-		links: make(map[string]*ShortLink),
+		links:    make(map[string]*ShortLink),
+		attempts: make(map[string]*passwordAttempts),
 	}
 }
 
@@ -62,6 +178,16 @@ func GenerateUniqueID() string {
 	return uniqueID
 }
 
+// randomID 从crypto/rand生成128位随机ID（32个十六进制字符）
+// 用于异步任务和批量会话的ID：并发创建时不会重复，ID同时作为文件名，重复会互相覆盖
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		panic(fmt.Sprintf("生成随机ID失败: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
 // 用于生成唯一ID的静态计数器
 var (
 	counterMutex sync.Mutex
@@ -79,6 +205,34 @@ func getAndIncrementCounter() uint64 {
 
 // CreateShortLink 创建新的短链接
 func (m *ShortLinkManager) CreateShortLink(longURL string, fileName string, expiration time.Time) (string, error) {
+	return m.CreateShortLinkWithOptions(longURL, fileName, expiration, ShortLinkOptions{})
+}
+
+// CreateShortLinkWithOptions 创建带访问控制（密码、下载次数、一次性）的短链接
+func (m *ShortLinkManager) CreateShortLinkWithOptions(longURL string, fileName string, expiration time.Time, opts ShortLinkOptions) (string, error) {
+	if opts.MaxDownloads < 0 {
+		return "", fmt.Errorf("最大下载次数不能为负数: %d", opts.MaxDownloads)
+	}
+	if len(opts.Objects) > 0 && opts.Prefix != "" {
+		return "", fmt.Errorf("对象列表和对象前缀不能同时指定")
+	}
+
+	// 一次性链接等价于最多下载一次
+	maxDownloads := opts.MaxDownloads
+	if opts.OneTime {
+		maxDownloads = 1
+	}
+
+	// 对访问密码进行哈希，避免明文保存
+	var passwordHash string
+	if opts.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("生成密码哈希失败: %w", err)
+		}
+		passwordHash = string(hash)
+	}
+
 	// 生成唯一ID
 	uniqueID := GenerateUniqueID()
 
@@ -88,20 +242,189 @@ func (m *ShortLinkManager) CreateShortLink(longURL string, fileName string, expi
 	// 完整的短链接ID格式为: uniqueID/encodedFileName
 	linkID := uniqueID + "/" + encodedFileName
 
+	objectName := opts.ObjectName
+	if objectName == "" {
+		objectName = fileName
+	}
+
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	m.links[uniqueID] = &ShortLink{
-		ID:         linkID,
-		UniqueID:   uniqueID,
-		LongURL:    longURL,
-		FileName:   fileName,
-		Expiration: expiration,
+		ID:           linkID,
+		UniqueID:     uniqueID,
+		LongURL:      longURL,
+		FileName:     fileName,
+		ObjectName:   objectName,
+		Backend:      opts.Backend,
+		Expiration:   expiration,
+		LandingPage:  opts.LandingPage,
+		PasswordHash: passwordHash,
+		MaxDownloads: maxDownloads,
+		OneTime:      opts.OneTime,
+		Objects:      append([]string(nil), opts.Objects...),
+		Prefix:       opts.Prefix,
 	}
 
 	return linkID, nil
 }
 
+// GetShortLink 获取短链接的副本，不计入下载次数
+// 先按唯一ID查找，找不到时按完整ID查找（兼容旧版格式）
+func (m *ShortLinkManager) GetShortLink(id string) (*ShortLink, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	link := m.findLink(id)
+	if link == nil || time.Now().After(link.Expiration) {
+		return nil, false
+	}
+
+	linkCopy := *link
+	return &linkCopy, true
+}
+
+// AccessShortLink 校验密码并计入一次下载，返回计数后的短链接副本
+// 密码校验在锁外进行，之后重新加锁检查有效期和下载次数并递增，保证并发访问时不会超出限制
+func (m *ShortLinkManager) AccessShortLink(id string, password string, clientIP string) (*ShortLink, error) {
+	uniqueID, err := m.verifyPassword(id, password, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	link, err := m.checkLink(uniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	link.Downloads++
+
+	linkCopy := *link
+	return &linkCopy, nil
+}
+
+// VerifyPassword 校验访问密码并检查链接状态，但不计入下载次数，用于展示受密码保护的详情页
+func (m *ShortLinkManager) VerifyPassword(id string, password string, clientIP string) (*ShortLink, error) {
+	uniqueID, err := m.verifyPassword(id, password, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	link, err := m.checkLink(uniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	linkCopy := *link
+	return &linkCopy, nil
+}
+
+// verifyPassword 校验访问密码，返回链接的唯一ID
+// 不检查有效期和下载次数，调用方在密码通过后再检查，未通过密码校验的请求无法得知受保护链接的状态
+// bcrypt比较耗时，只在锁内复制密码哈希，比较时不持有锁
+func (m *ShortLinkManager) verifyPassword(id string, password string, clientIP string) (string, error) {
+	m.mutex.RLock()
+	link := m.findLink(id)
+	if link == nil {
+		m.mutex.RUnlock()
+		return "", ErrLinkNotFound
+	}
+	uniqueID, passwordHash := link.UniqueID, link.PasswordHash
+	m.mutex.RUnlock()
+
+	if passwordHash == "" {
+		return uniqueID, nil
+	}
+	if password == "" {
+		return "", ErrPasswordRequired
+	}
+
+	// 先占用尝试次数再比较，避免并发请求绕过限制
+	clientKey := uniqueID + "|" + clientIP
+	if !m.takeAttempt(clientKey) {
+		return "", ErrTooManyAttempts
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		return "", ErrPasswordIncorrect
+	}
+	m.releaseAttempts(clientKey)
+
+	return uniqueID, nil
+}
+
+// checkLink 检查链接是否过期或下载次数已用完，调用方需持有写锁
+func (m *ShortLinkManager) checkLink(uniqueID string) (*ShortLink, error) {
+	link := m.links[uniqueID]
+	if link == nil {
+		return nil, ErrLinkNotFound
+	}
+
+	// 检查是否已过期
+	if time.Now().After(link.Expiration) {
+		delete(m.links, link.UniqueID)
+		return nil, ErrLinkExpired
+	}
+
+	// 下载次数用完的链接保留到过期，以便返回410而不是404
+	if link.isExhausted() {
+		return nil, ErrLinkExhausted
+	}
+
+	return link, nil
+}
+
+// takeAttempt 占用一次密码尝试，客户端在窗口期内的尝试次数超出上限时返回false
+func (m *ShortLinkManager) takeAttempt(clientKey string) bool {
+	m.attemptMutex.Lock()
+	defer m.attemptMutex.Unlock()
+
+	attempts := m.currentAttempts(clientKey, time.Now())
+	if attempts.count >= maxPasswordAttemptsPerIP {
+		return false
+	}
+	attempts.count++
+	return true
+}
+
+// releaseAttempts 密码正确时清空该客户端的失败记录
+func (m *ShortLinkManager) releaseAttempts(clientKey string) {
+	m.attemptMutex.Lock()
+	defer m.attemptMutex.Unlock()
+
+	delete(m.attempts, clientKey)
+}
+
+// currentAttempts 返回键对应的尝试记录，窗口期已过时重新计数，调用方需持有attemptMutex
+func (m *ShortLinkManager) currentAttempts(key string, now time.Time) *passwordAttempts {
+	attempts, ok := m.attempts[key]
+	if !ok || now.Sub(attempts.windowStart) > passwordAttemptWindow {
+		attempts = &passwordAttempts{windowStart: now}
+		m.attempts[key] = attempts
+	}
+	return attempts
+}
+
+// findLink 按唯一ID或完整ID查找短链接，调用方需持有锁
+func (m *ShortLinkManager) findLink(id string) *ShortLink {
+	if link, exists := m.links[id]; exists {
+		return link
+	}
+
+	for _, link := range m.links {
+		if link.ID == id {
+			return link
+		}
+	}
+
+	return nil
+}
+
 // GetLongURL 获取短链接对应的长链接 (通过完整ID查找)
 func (m *ShortLinkManager) GetLongURL(id string) (string, string, bool) {
 	return m.getLongURLByID(id)
@@ -172,4 +495,13 @@ func (m *ShortLinkManager) CleanupExpiredLinks() {
 			delete(m.links, id)
 		}
 	}
+
+	// 同时清理窗口期已过的密码尝试记录
+	m.attemptMutex.Lock()
+	defer m.attemptMutex.Unlock()
+	for key, attempts := range m.attempts {
+		if now.Sub(attempts.windowStart) > passwordAttemptWindow {
+			delete(m.attempts, key)
+		}
+	}
 }
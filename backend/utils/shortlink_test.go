@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newProtectedLink 创建带访问密码的短链接，返回唯一ID
+func newProtectedLink(t *testing.T, m *ShortLinkManager, opts ShortLinkOptions) string {
+	t.Helper()
+	opts.Password = "secret"
+	if _, err := m.CreateShortLinkWithOptions("https://example.com/a.txt", "a.txt", time.Now().Add(time.Hour), opts); err != nil {
+		t.Fatal(err)
+	}
+	for uniqueID := range m.links {
+		return uniqueID
+	}
+	t.Fatal("短链接没有保存")
+	return ""
+}
+
+func TestPasswordAttemptsPerClient(t *testing.T) {
+	m := NewShortLinkManager()
+	uniqueID := newProtectedLink(t, m, ShortLinkOptions{})
+
+	steps := []struct {
+		name     string
+		clientIP string
+		password string
+		want     error
+	}{
+		{"未提供密码不计入尝试次数", "10.0.0.1", "", ErrPasswordRequired},
+		{"第1次错误", "10.0.0.1", "wrong", ErrPasswordIncorrect},
+		{"第2次错误", "10.0.0.1", "wrong", ErrPasswordIncorrect},
+		{"第3次错误", "10.0.0.1", "wrong", ErrPasswordIncorrect},
+		{"第4次错误", "10.0.0.1", "wrong", ErrPasswordIncorrect},
+		{"第5次错误", "10.0.0.1", "wrong", ErrPasswordIncorrect},
+		{"超出次数后正确的密码也被拒绝", "10.0.0.1", "secret", ErrTooManyAttempts},
+		{"其他客户端不受影响", "10.0.0.2", "secret", nil},
+		{"其他客户端错误4次", "10.0.0.3", "wrong", ErrPasswordIncorrect},
+		{"其他客户端错误4次", "10.0.0.3", "wrong", ErrPasswordIncorrect},
+		{"其他客户端错误4次", "10.0.0.3", "wrong", ErrPasswordIncorrect},
+		{"其他客户端错误4次", "10.0.0.3", "wrong", ErrPasswordIncorrect},
+		{"第5次使用正确的密码", "10.0.0.3", "secret", nil},
+		{"密码正确后重新计数", "10.0.0.3", "wrong", ErrPasswordIncorrect},
+		{"密码正确后重新计数", "10.0.0.3", "secret", nil},
+	}
+	for _, step := range steps {
+		_, err := m.VerifyPassword(uniqueID, step.password, step.clientIP)
+		if !errors.Is(err, step.want) || (step.want == nil && err != nil) {
+			t.Fatalf("%s: %s 返回 %v，期望 %v", step.name, step.clientIP, err, step.want)
+		}
+	}
+
+	// 窗口期过后重新计数
+	m.attempts[uniqueID+"|10.0.0.1"].windowStart = time.Now().Add(-passwordAttemptWindow - time.Second)
+	if _, err := m.VerifyPassword(uniqueID, "secret", "10.0.0.1"); err != nil {
+		t.Errorf("窗口期过后使用正确的密码返回 %v", err)
+	}
+}
+
+func TestPasswordCheckedBeforeLinkState(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     ShortLinkOptions
+		modify   func(link *ShortLink)
+		password string
+		want     error
+	}{
+		{"下载次数用完，密码错误", ShortLinkOptions{OneTime: true}, func(l *ShortLink) { l.Downloads = 1 }, "wrong", ErrPasswordIncorrect},
+		{"下载次数用完，未提供密码", ShortLinkOptions{OneTime: true}, func(l *ShortLink) { l.Downloads = 1 }, "", ErrPasswordRequired},
+		{"下载次数用完，密码正确", ShortLinkOptions{OneTime: true}, func(l *ShortLink) { l.Downloads = 1 }, "secret", ErrLinkExhausted},
+		{"已过期，密码错误", ShortLinkOptions{}, func(l *ShortLink) { l.Expiration = time.Now().Add(-time.Second) }, "wrong", ErrPasswordIncorrect},
+		{"已过期，密码正确", ShortLinkOptions{}, func(l *ShortLink) { l.Expiration = time.Now().Add(-time.Second) }, "secret", ErrLinkExpired},
+		{"有效，密码正确", ShortLinkOptions{MaxDownloads: 2}, func(l *ShortLink) { l.Downloads = 1 }, "secret", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewShortLinkManager()
+			uniqueID := newProtectedLink(t, m, tt.opts)
+			tt.modify(m.links[uniqueID])
+
+			link, err := m.AccessShortLink(uniqueID, tt.password, "10.0.0.1")
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("返回 %v，期望成功", err)
+				}
+				if link.Downloads != 2 {
+					t.Errorf("下载次数为 %d，期望 2", link.Downloads)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("返回 %v，期望 %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/time/rate"
+)
+
+// minThrottleBurst 令牌桶的最小容量，需要不小于限速读取器单次读取的字节数
+const minThrottleBurst = 64 * 1024
+
+// ThrottleWindow 按时段生效的全局带宽限制
+// Start、End 为一天中的分钟数，End 小于 Start 时表示跨越午夜
+type ThrottleWindow struct {
+	Start int
+	End   int
+	KBps  int // 时段内的全局限速 (KB/s)，0表示不限速
+}
+
+// contains 返回指定时刻是否处于该时段内
+func (w ThrottleWindow) contains(minute int) bool {
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// ThrottleConfig 带宽限速配置，速率单位为KB/s，0表示不限速
+type ThrottleConfig struct {
+	GlobalKBps    int              // 所有上传合计的带宽
+	PerIPKBps     int              // 同一客户端IP的所有上传合计的带宽
+	PerUploadKBps int              // 单个上传的带宽
+	Schedule      []ThrottleWindow // 按时段覆盖全局带宽，例如工作时间限速
+}
+
+// hasGlobalLimit 返回是否在任一时刻存在全局限速
+func (c *ThrottleConfig) hasGlobalLimit() bool {
+	if c.GlobalKBps > 0 {
+		return true
+	}
+	for _, window := range c.Schedule {
+		if window.KBps > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadThrottleConfigFromEnv 从环境变量加载带宽限速配置
+func LoadThrottleConfigFromEnv() (*ThrottleConfig, error) {
+	// 尝试加载.env文件，但不强制要求
+	_ = godotenv.Load()
+
+	config := &ThrottleConfig{
+		GlobalKBps:    envInt("THROTTLE_GLOBAL_KBPS", 0),
+		PerIPKBps:     envInt("THROTTLE_PER_IP_KBPS", 0),
+		PerUploadKBps: envInt("THROTTLE_PER_UPLOAD_KBPS", 0),
+	}
+
+	schedule, err := ParseThrottleSchedule(os.Getenv("THROTTLE_SCHEDULE"))
+	if err != nil {
+		return nil, fmt.Errorf("带宽限速时段配置错误: %w", err)
+	}
+	config.Schedule = schedule
+
+	return config, nil
+}
+
+// ParseThrottleSchedule 解析按时段的全局限速配置
+// 格式为逗号分隔的 "HH:MM-HH:MM=KB/s"，例如 "09:00-18:00=2048,18:00-09:00=0"
+func ParseThrottleSchedule(spec string) ([]ThrottleWindow, error) {
+	var windows []ThrottleWindow
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		period, kbps, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("缺少速率: %s", item)
+		}
+		startStr, endStr, ok := strings.Cut(period, "-")
+		if !ok {
+			return nil, fmt.Errorf("时段格式应为HH:MM-HH:MM: %s", item)
+		}
+
+		start, err := parseClock(startStr)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(endStr)
+		if err != nil {
+			return nil, err
+		}
+		rateKBps, err := strconv.Atoi(strings.TrimSpace(kbps))
+		if err != nil || rateKBps < 0 {
+			return nil, fmt.Errorf("速率必须是非负整数: %s", item)
+		}
+
+		windows = append(windows, ThrottleWindow{Start: start, End: end, KBps: rateKBps})
+	}
+	return windows, nil
+}
+
+// parseClock 将 HH:MM 解析为一天中的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为HH:MM: %s", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// BandwidthThrottle 带宽限速器
+// 基于令牌桶，全局、每个客户端IP和每个上传的限速同时生效，按时段的全局限速在读取时动态切换
+type BandwidthThrottle struct {
+	mutex      sync.Mutex
+	config     *ThrottleConfig
+	global     *rate.Limiter
+	globalKBps int
+	perIP      map[string]*ipLimiter
+}
+
+// ipLimiter 单个客户端IP的令牌桶
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// NewBandwidthThrottle 创建新的带宽限速器
+func NewBandwidthThrottle(config *ThrottleConfig) *BandwidthThrottle {
+	return &BandwidthThrottle{
+		config: config,
+		perIP:  make(map[string]*ipLimiter),
+	}
+}
+
+// GlobalKBps 返回指定时刻生效的全局限速 (KB/s)，0表示不限速
+func (t *BandwidthThrottle) GlobalKBps(now time.Time) int {
+	minute := now.Hour()*60 + now.Minute()
+	for _, window := range t.config.Schedule {
+		if window.contains(minute) {
+			return window.KBps
+		}
+	}
+	return t.config.GlobalKBps
+}
+
+// Limiter 为一次上传创建限速器，单个上传、客户端IP和全局限速都不生效时返回nil
+// clientIP 为空时不应用按IP的限速；requestedKBps 为客户端要求的单个上传限速，只能比配置更严格
+// 全局限速按时段切换，只要任一时段存在全局限速就返回限速器
+func (t *BandwidthThrottle) Limiter(clientIP string, requestedKBps int) *UploadLimiter {
+	uploadKBps := t.config.PerUploadKBps
+	if requestedKBps > 0 && (uploadKBps == 0 || requestedKBps < uploadKBps) {
+		uploadKBps = requestedKBps
+	}
+	if uploadKBps == 0 && (clientIP == "" || t.config.PerIPKBps == 0) && !t.config.hasGlobalLimit() {
+		return nil
+	}
+
+	limiter := &UploadLimiter{throttle: t}
+	if uploadKBps > 0 {
+		limiter.upload = newKBpsLimiter(uploadKBps)
+	}
+
+	if clientIP != "" && t.config.PerIPKBps > 0 {
+		t.mutex.Lock()
+		entry, ok := t.perIP[clientIP]
+		if !ok {
+			entry = &ipLimiter{limiter: newKBpsLimiter(t.config.PerIPKBps)}
+			t.perIP[clientIP] = entry
+		}
+		entry.lastUsed = time.Now()
+		limiter.ip = entry.limiter
+		t.mutex.Unlock()
+	}
+
+	return limiter
+}
+
+// CleanupIdle 清理长时间没有新上传的客户端IP令牌桶
+func (t *BandwidthThrottle) CleanupIdle(maxIdle time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	cutoff := time.Now().Add(-maxIdle)
+	for ip, entry := range t.perIP {
+		if entry.lastUsed.Before(cutoff) {
+			delete(t.perIP, ip)
+		}
+	}
+}
+
+// globalLimiter 返回当前时段的全局令牌桶，不限速时返回nil
+func (t *BandwidthThrottle) globalLimiter() *rate.Limiter {
+	kbps := t.GlobalKBps(time.Now())
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if kbps != t.globalKBps {
+		t.globalKBps = kbps
+		switch {
+		case kbps == 0:
+			t.global = nil
+		case t.global == nil:
+			t.global = newKBpsLimiter(kbps)
+		default:
+			t.global.SetLimit(kbpsLimit(kbps))
+			t.global.SetBurst(kbpsBurst(kbps))
+		}
+	}
+	return t.global
+}
+
+// UploadLimiter 单个上传使用的限速器，依次等待全局、客户端IP和上传自身的令牌
+type UploadLimiter struct {
+	throttle *BandwidthThrottle
+	ip       *rate.Limiter
+	upload   *rate.Limiter
+}
+
+// WaitN 等待n个字节的令牌，上下文结束时返回错误
+func (l *UploadLimiter) WaitN(ctx context.Context, n int) error {
+	if global := l.throttle.globalLimiter(); global != nil {
+		if err := global.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	if l.ip != nil {
+		if err := l.ip.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	if l.upload != nil {
+		if err := l.upload.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newKBpsLimiter 创建指定速率 (KB/s) 的令牌桶，初始即装满
+func newKBpsLimiter(kbps int) *rate.Limiter {
+	return rate.NewLimiter(kbpsLimit(kbps), kbpsBurst(kbps))
+}
+
+// kbpsLimit 将KB/s转换为令牌桶的速率（字节/秒）
+func kbpsLimit(kbps int) rate.Limit {
+	return rate.Limit(kbps * 1024)
+}
+
+// kbpsBurst 令牌桶容量为一秒的流量，且不小于最小容量
+func kbpsBurst(kbps int) int {
+	if burst := kbps * 1024; burst > minThrottleBurst {
+		return burst
+	}
+	return minThrottleBurst
+}
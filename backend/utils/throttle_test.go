@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseThrottleSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []ThrottleWindow
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"09:00-18:00=2048", []ThrottleWindow{{Start: 540, End: 1080, KBps: 2048}}, false},
+		{" 09:00-18:00=2048 , 18:00-09:00=0 ", []ThrottleWindow{{540, 1080, 2048}, {1080, 540, 0}}, false},
+		{"09:00-18:00", nil, true},
+		{"09:00=2048", nil, true},
+		{"9am-18:00=2048", nil, true},
+		{"09:00-24:30=2048", nil, true},
+		{"09:00-18:00=-1", nil, true},
+		{"09:00-18:00=fast", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseThrottleSchedule(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseThrottleSchedule(%q) 错误为 %v，期望错误: %v", tt.spec, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseThrottleSchedule(%q) = %v，期望 %v", tt.spec, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseThrottleSchedule(%q) = %v，期望 %v", tt.spec, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGlobalKBpsSchedule(t *testing.T) {
+	schedule, err := ParseThrottleSchedule("09:00-18:00=2048,22:00-06:00=8192")
+	if err != nil {
+		t.Fatal(err)
+	}
+	throttle := NewBandwidthThrottle(&ThrottleConfig{GlobalKBps: 4096, Schedule: schedule})
+
+	tests := []struct {
+		clock string
+		want  int
+	}{
+		{"08:59", 4096},
+		{"09:00", 2048},
+		{"17:59", 2048},
+		{"18:00", 4096},
+		{"22:00", 8192},
+		{"00:30", 8192},
+		{"06:00", 4096},
+	}
+	for _, tt := range tests {
+		now, err := time.Parse("15:04", tt.clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := throttle.GlobalKBps(now); got != tt.want {
+			t.Errorf("%s 的全局限速为 %d，期望 %d", tt.clock, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleLimiter(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     ThrottleConfig
+		clientIP   string
+		requested  int
+		wantNil    bool
+		wantIP     bool
+		wantUpload int // 单个上传的限速 (KB/s)，0表示没有
+	}{
+		{"不限速", ThrottleConfig{}, "10.0.0.1", 0, true, false, 0},
+		{"没有客户端IP时不按IP限速", ThrottleConfig{PerIPKBps: 512}, "", 0, true, false, 0},
+		{"按IP限速", ThrottleConfig{PerIPKBps: 512}, "10.0.0.1", 0, false, true, 0},
+		{"只有按时段的全局限速", ThrottleConfig{Schedule: []ThrottleWindow{{Start: 0, End: 60, KBps: 100}}}, "", 0, false, false, 0},
+		{"按时段的全局限速都为0", ThrottleConfig{Schedule: []ThrottleWindow{{Start: 0, End: 60, KBps: 0}}}, "", 0, true, false, 0},
+		{"客户端要求的限速", ThrottleConfig{}, "", 256, false, false, 256},
+		{"客户端要求更严格的限速", ThrottleConfig{PerUploadKBps: 1024}, "", 256, false, false, 256},
+		{"客户端要求更宽松的限速时使用配置", ThrottleConfig{PerUploadKBps: 1024}, "", 4096, false, false, 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewBandwidthThrottle(&tt.config).Limiter(tt.clientIP, tt.requested)
+			if tt.wantNil {
+				if limiter != nil {
+					t.Errorf("期望不限速，返回了限速器")
+				}
+				return
+			}
+			if limiter == nil {
+				t.Fatal("期望限速，返回nil")
+			}
+			if (limiter.ip != nil) != tt.wantIP {
+				t.Errorf("按IP限速: %v，期望 %v", limiter.ip != nil, tt.wantIP)
+			}
+			switch {
+			case tt.wantUpload == 0 && limiter.upload != nil:
+				t.Errorf("不应有单个上传的限速")
+			case tt.wantUpload > 0 && (limiter.upload == nil || limiter.upload.Limit() != kbpsLimit(tt.wantUpload)):
+				t.Errorf("单个上传的限速不是 %d KB/s", tt.wantUpload)
+			}
+		})
+	}
+}
+
+// canTake 返回在很短的截止时间内能否取得n个字节的令牌
+// 令牌桶在等待时间超过截止时间时立即返回错误，测试不需要真正等待
+func canTake(t *testing.T, limiter *UploadLimiter, n int) bool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	return limiter.WaitN(ctx, n) == nil
+}
+
+func TestUploadLimiterTokenBucket(t *testing.T) {
+	throttle := NewBandwidthThrottle(&ThrottleConfig{PerIPKBps: 128})
+	first := throttle.Limiter("10.0.0.1", 0)
+
+	// 初始即装满一秒的流量，超出后需要等待
+	if err := first.WaitN(context.Background(), 128*1024); err != nil {
+		t.Fatalf("取得初始令牌失败: %v", err)
+	}
+	if canTake(t, first, 64*1024) {
+		t.Error("令牌用完后没有等待")
+	}
+
+	// 同一IP的上传共用令牌桶，其他IP不受影响
+	if canTake(t, throttle.Limiter("10.0.0.1", 0), 64*1024) {
+		t.Error("同一IP的另一个上传没有共用令牌桶")
+	}
+	if !canTake(t, throttle.Limiter("10.0.0.2", 0), 64*1024) {
+		t.Error("其他IP的上传被限速")
+	}
+
+	// 长时间没有新上传的IP令牌桶被清理
+	throttle.perIP["10.0.0.1"].lastUsed = time.Now().Add(-time.Hour)
+	throttle.CleanupIdle(10 * time.Minute)
+	if _, ok := throttle.perIP["10.0.0.1"]; ok {
+		t.Error("空闲的IP令牌桶没有被清理")
+	}
+	if _, ok := throttle.perIP["10.0.0.2"]; !ok {
+		t.Error("仍在使用的IP令牌桶被清理")
+	}
+}
+
+func TestKBpsBurst(t *testing.T) {
+	tests := []struct {
+		kbps int
+		want int
+	}{
+		{1, minThrottleBurst},
+		{64, minThrottleBurst},
+		{65, 65 * 1024},
+		{2048, 2048 * 1024},
+	}
+	for _, tt := range tests {
+		if got := kbpsBurst(tt.kbps); got != tt.want {
+			t.Errorf("kbpsBurst(%d) = %d，期望 %d", tt.kbps, got, tt.want)
+		}
+	}
+}
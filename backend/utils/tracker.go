@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 上传控制相关错误定义
+var (
+	// ErrUploadCanceled 上传已被客户端取消
+	ErrUploadCanceled = errors.New("上传已取消")
+
+	// ErrUploadNotFound 上传任务不存在或已结束
+	ErrUploadNotFound = errors.New("上传任务不存在或已结束")
+
+	// ErrUploadInProgress 相同上传ID的任务正在进行
+	ErrUploadInProgress = errors.New("相同上传ID的任务正在进行")
+
+	// ErrInvalidAction 不支持的控制操作
+	ErrInvalidAction = errors.New("不支持的控制操作，可选: cancel, pause, resume")
+)
+
+// 上传控制操作
+const (
+	ActionCancel = "cancel"
+	ActionPause  = "pause"
+	ActionResume = "resume"
+)
+
+// TrackedUpload 可取消、暂停和恢复的上传任务
+// 取消会结束整个上传的上下文；暂停只取消当前这一次向存储服务的上传尝试，
+// 恢复后由调用方重新发起上传（阿里云OSS会借助断点续传记录从已完成的分片继续）
+type TrackedUpload struct {
+	id     string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mutex         sync.Mutex
+	paused        bool
+	resumed       chan struct{}      // 暂停期间有效，恢复时关闭
+	attemptCancel context.CancelFunc // 当前上传尝试的取消函数
+}
+
+// ID 返回上传ID
+func (u *TrackedUpload) ID() string {
+	return u.id
+}
+
+// Context 返回整个上传任务的上下文，取消上传时结束
+func (u *TrackedUpload) Context() context.Context {
+	return u.ctx
+}
+
+// Err 返回上传被取消的原因，未取消时返回nil
+func (u *TrackedUpload) Err() error {
+	if u.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(u.ctx)
+}
+
+// Cancel 取消上传
+func (u *TrackedUpload) Cancel() {
+	u.cancel(ErrUploadCanceled)
+}
+
+// Pause 暂停上传，中断当前的上传尝试，返回状态是否发生变化
+func (u *TrackedUpload) Pause() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.paused {
+		return false
+	}
+	u.paused = true
+	u.resumed = make(chan struct{})
+	if u.attemptCancel != nil {
+		u.attemptCancel()
+	}
+	return true
+}
+
+// Resume 恢复已暂停的上传，返回状态是否发生变化
+func (u *TrackedUpload) Resume() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !u.paused {
+		return false
+	}
+	u.paused = false
+	close(u.resumed)
+	return true
+}
+
+// Paused 返回上传是否处于暂停状态
+func (u *TrackedUpload) Paused() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.paused
+}
+
+// WaitResumed 在暂停期间阻塞，直到恢复或上传被取消
+func (u *TrackedUpload) WaitResumed() error {
+	u.mutex.Lock()
+	if !u.paused {
+		u.mutex.Unlock()
+		return u.Err()
+	}
+	resumed := u.resumed
+	u.mutex.Unlock()
+
+	select {
+	case <-resumed:
+		return u.Err()
+	case <-u.ctx.Done():
+		return u.Err()
+	}
+}
+
+// BeginAttempt 为一次向存储服务的上传尝试创建上下文，暂停时该上下文会被取消
+func (u *TrackedUpload) BeginAttempt() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(u.ctx)
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	// 在创建尝试前已被暂停，直接取消该尝试
+	if u.paused {
+		cancel()
+	}
+	u.attemptCancel = cancel
+	return ctx, cancel
+}
+
+// UploadTracker 按上传ID登记正在进行的上传任务
+type UploadTracker struct {
+	mutex   sync.Mutex
+	uploads map[string]*TrackedUpload
+}
+
+// NewUploadTracker 创建新的上传任务登记器
+func NewUploadTracker() *UploadTracker {
+	return &UploadTracker{
+		uploads: make(map[string]*TrackedUpload),
+	}
+}
+
+// Register 登记上传任务，任务上下文派生自parent
+func (t *UploadTracker) Register(parent context.Context, id string) (*TrackedUpload, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, exists := t.uploads[id]; exists {
+		return nil, ErrUploadInProgress
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	upload := &TrackedUpload{
+		id:     id,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	t.uploads[id] = upload
+	return upload, nil
+}
+
+// Unregister 移除上传任务并释放其上下文
+func (t *UploadTracker) Unregister(id string) {
+	t.mutex.Lock()
+	upload, exists := t.uploads[id]
+	delete(t.uploads, id)
+	t.mutex.Unlock()
+
+	if exists {
+		upload.cancel(context.Canceled)
+	}
+}
+
+// Get 获取正在进行的上传任务
+func (t *UploadTracker) Get(id string) (*TrackedUpload, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	upload, exists := t.uploads[id]
+	return upload, exists
+}
+
+// Control 对上传任务执行控制操作（cancel、pause、resume）
+func (t *UploadTracker) Control(id string, action string) error {
+	upload, exists := t.Get(id)
+	if !exists {
+		return ErrUploadNotFound
+	}
+
+	switch action {
+	case ActionCancel:
+		upload.Cancel()
+	case ActionPause:
+		upload.Pause()
+	case ActionResume:
+		upload.Resume()
+	default:
+		return ErrInvalidAction
+	}
+	return nil
+}